@@ -7,11 +7,13 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
 	"cloud.google.com/go/firestore"
 	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/dgravesa/bark/pkg/delivery"
 	"github.com/dgravesa/bark/pkg/dog"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -34,7 +36,9 @@ func init() {
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(15 * time.Second))
+
+	// long-lived streams are registered outside of the request timeout
+	api := r.With(middleware.Timeout(15 * time.Second))
 
 	// initialize dependencies
 	firestoreClient, err := firestore.NewClient(context.Background(), projectID)
@@ -48,6 +52,85 @@ func init() {
 	doggoStore := &dog.DoggoFirestore{
 		FirestoreClient: firestoreClient,
 	}
+	userStore := &dog.UserFirestore{
+		FirestoreClient: firestoreClient,
+	}
+	inboxStore := &dog.InboxFirestore{
+		FirestoreClient: firestoreClient,
+	}
+	digestStore := &dog.DigestFirestore{
+		FirestoreClient: firestoreClient,
+	}
+	calendarStore := &dog.CalendarFirestore{
+		FirestoreClient: firestoreClient,
+	}
+
+	// initialize delivery channels
+	barkers := &dog.BarkerRegistry{
+		DefaultChannel: "log",
+	}
+	barkers.Register("log", &delivery.LogBarker{
+		Logger: logger,
+	})
+	barkers.Register("inbox", &delivery.InboxBarker{
+		Inbox: inboxStore,
+	})
+	webhookClient := delivery.NewPublicClient(10 * time.Second)
+	barkers.Register("webhook", &delivery.WebhookBarker{
+		Client: webhookClient,
+	})
+	barkers.Register("slack", &delivery.WebhookBarker{
+		Format:   delivery.FormatSlack,
+		Unsigned: true,
+		Client:   webhookClient,
+	})
+	barkers.Register("discord", &delivery.WebhookBarker{
+		Format:   delivery.FormatDiscord,
+		Unsigned: true,
+		Client:   webhookClient,
+	})
+	if vapidPrivateKey := os.Getenv("VAPID_PRIVATE_KEY"); vapidPrivateKey != "" {
+		vapidKeys, err := delivery.ParseVAPIDKeys(vapidPrivateKey, os.Getenv("VAPID_SUBJECT"))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		pushSubscriptionStore := &delivery.PushSubscriptionFirestore{
+			FirestoreClient: firestoreClient,
+		}
+		barkers.Register("webpush", &delivery.WebPushBarker{
+			VAPID:         vapidKeys,
+			Subscriptions: pushSubscriptionStore,
+			Client:        webhookClient,
+			TTL:           24 * time.Hour,
+		})
+		pushService := delivery.WebPushService{
+			Service: bark.Service{
+				Name:   "bark-dogs",
+				Logger: logger,
+			},
+			VAPID:         vapidKeys,
+			Subscriptions: pushSubscriptionStore,
+		}
+		pushService.RegisterRoutes(api)
+	}
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		barkers.Register("email", &delivery.EmailBarker{
+			Addr:     smtpAddr,
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			StartTLS: os.Getenv("SMTP_STARTTLS") == "true",
+		})
+	}
+
+	retryPolicy := dog.DefaultRetryPolicy
+	if maxAttempts := os.Getenv("MAX_DELIVERY_ATTEMPTS"); maxAttempts != "" {
+		retryPolicy.MaxAttempts, err = strconv.Atoi(maxAttempts)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
 	// initialize service
 	service := dog.Service{
 		Service: bark.Service{
@@ -62,10 +145,28 @@ func init() {
 			QueueName:  os.Getenv("QUEUE_NAME"),
 			TaskClient: tasksClient,
 			DogStore:   doggoStore,
+			Digests:    digestStore,
+			Users:      userStore,
+			Calendars:  calendarStore,
+		},
+		Barkers: barkers,
+		BarkStore: &dog.BarkFirestore{
+			FirestoreClient: firestoreClient,
+		},
+		RetryPolicy: retryPolicy,
+		UserStore:   userStore,
+		Hub:         &dog.BarkHub{},
+		Inbox:       inboxStore,
+		Digests:     digestStore,
+		Calendars:   calendarStore,
+		DeadLetters: &dog.DeadLetterFirestore{
+			FirestoreClient: firestoreClient,
 		},
+		AdminToken: os.Getenv("ADMIN_TOKEN"),
 	}
 
-	service.RegisterRoutes(r)
+	service.RegisterRoutes(api)
+	service.RegisterStreamRoutes(r)
 }
 
 func main() {
@@ -5,6 +5,9 @@ import (
 	"net/http"
 )
 
+// ContentTypeJSON is the content type of JSON bodies written by Bark services.
+const ContentTypeJSON = "application/json"
+
 type errorType struct {
 	Text string `json:"errorText"`
 }
@@ -15,14 +18,14 @@ func RespondError(w http.ResponseWriter, code int, errorText string) {
 		Text: errorText,
 	}
 	b, _ := json.Marshal(e)
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", ContentTypeJSON)
 	w.WriteHeader(code)
 	w.Write(b)
 }
 
 // RespondSuccess is a helper to respond with a successful JSON response.
 func RespondSuccess(w http.ResponseWriter, code int, v interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", ContentTypeJSON)
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(v)
 }
@@ -0,0 +1,153 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// Message length limits imposed by chat incoming webhooks.
+const (
+	slackMaxSectionText     = 3000
+	discordMaxEmbedDescText = 4096
+)
+
+// discordBarkColor is the embed accent color used for barks.
+const discordBarkColor = 0xE8A33D
+
+// SlackMessage is a Slack incoming-webhook message using Block Kit.
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+// SlackBlock is a Slack Block Kit layout block.
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+// SlackText is a Slack Block Kit text object.
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// FormatSlack is a WebhookFormatter for Slack incoming webhooks. The idea is rendered as a quote
+// section with a context footer describing the dog.
+func FormatSlack(b *dog.Bark) (interface{}, error) {
+	return &SlackMessage{
+		Text: truncate(b.Idea.Text, slackMaxSectionText),
+		Blocks: []SlackBlock{
+			{
+				Type: "section",
+				Text: &SlackText{Type: "mrkdwn", Text: quoteSlack(b.Idea.Text, slackMaxSectionText)},
+			},
+			{
+				Type: "context",
+				Elements: []SlackText{
+					{Type: "mrkdwn", Text: escapeSlack(chatFooter(b))},
+				},
+			},
+		},
+	}, nil
+}
+
+// DiscordMessage is a Discord incoming-webhook message.
+type DiscordMessage struct {
+	Embeds []DiscordEmbed `json:"embeds"`
+}
+
+// DiscordEmbed is a Discord message embed.
+type DiscordEmbed struct {
+	Description string              `json:"description"`
+	Color       int                 `json:"color,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+	Footer      *DiscordEmbedFooter `json:"footer,omitempty"`
+}
+
+// DiscordEmbedFooter is the footer of a Discord message embed.
+type DiscordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// FormatDiscord is a WebhookFormatter for Discord webhooks. The idea is rendered as an embed with a
+// footer describing the dog.
+func FormatDiscord(b *dog.Bark) (interface{}, error) {
+	embed := DiscordEmbed{
+		Description: truncate(b.Idea.Text, discordMaxEmbedDescText),
+		Color:       discordBarkColor,
+		Footer:      &DiscordEmbedFooter{Text: chatFooter(b)},
+	}
+	if !b.ScheduledTime.IsZero() {
+		embed.Timestamp = b.ScheduledTime.Format(time.RFC3339)
+	}
+
+	return &DiscordMessage{
+		Embeds: []DiscordEmbed{embed},
+	}, nil
+}
+
+// chatFooter describes the schedule and creation time of a bark's dog, or of its digest.
+func chatFooter(b *dog.Bark) string {
+	if b.Digest != nil {
+		return fmt.Sprintf("digest of %d · %s · digest created %s", len(b.Digest),
+			describeSchedule(b.Dog), b.Dog.CreationTime.Format("Jan 2, 2006"))
+	}
+	return fmt.Sprintf("%s · dog created %s",
+		describeSchedule(b.Dog), b.Dog.CreationTime.Format("Jan 2, 2006"))
+}
+
+// describeSchedule returns a short human-readable description of a dog's schedule.
+func describeSchedule(d *dog.Dog) string {
+	var spec string
+	if err := json.Unmarshal(d.ScheduleRaw, &spec); err != nil {
+		spec = string(d.ScheduleRaw)
+	}
+	return fmt.Sprintf("%s: %s", d.ScheduleType, spec)
+}
+
+// escapeSlack escapes the control characters of Slack mrkdwn text.
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// quoteSlack escapes s as a Slack mrkdwn quote of at most n runes, marking truncation with an
+// ellipsis. Escape sequences and quote markers are never split.
+func quoteSlack(s string, n int) string {
+	quoted := "> " + strings.ReplaceAll(escapeSlack(s), "\n", "\n> ")
+	if utf8.RuneCountInString(quoted) <= n {
+		return quoted
+	}
+
+	var truncated strings.Builder
+	truncated.WriteString("> ")
+	length := 2
+	for _, r := range s {
+		piece := escapeSlack(string(r))
+		if r == '\n' {
+			piece = "\n> "
+		}
+		if length+utf8.RuneCountInString(piece) > n-1 {
+			break
+		}
+		truncated.WriteString(piece)
+		length += utf8.RuneCountInString(piece)
+	}
+	truncated.WriteString("…")
+	return truncated.String()
+}
+
+// truncate shortens s to at most n runes, marking truncation with an ellipsis.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,102 @@
+package delivery
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+func TestFormatSlack(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantQuoted string
+	}{
+		{name: "short text", text: "drink water", wantQuoted: "> drink water"},
+		{name: "escaped text", text: "a <b> & c", wantQuoted: "> a &lt;b&gt; &amp; c"},
+		{name: "multiline text", text: "one\ntwo", wantQuoted: "> one\n> two"},
+		{name: "long text", text: strings.Repeat("bark ", 1000)},
+		{name: "long escaped text", text: strings.Repeat("<&>", 1000)},
+		{name: "long multiline text", text: strings.Repeat("\n", 2000)},
+		{name: "long emoji text", text: strings.Repeat("🐕", 3000)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := testBark("")
+			b.Idea.Text = test.text
+			formatted, err := FormatSlack(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			message := formatted.(*SlackMessage)
+			quoted := message.Blocks[0].Text.Text
+
+			if n := utf8.RuneCountInString(quoted); n > slackMaxSectionText {
+				t.Errorf("quoted text has %d characters, want at most %d", n, slackMaxSectionText)
+			}
+			if test.wantQuoted != "" && quoted != test.wantQuoted {
+				t.Errorf("quoted text = %q, want %q", quoted, test.wantQuoted)
+			}
+			if test.wantQuoted == "" {
+				// the kept text is quoted whole, without a split escape sequence or marker
+				kept := strings.TrimSuffix(quoted, "…")
+				if kept == quoted {
+					t.Fatalf("quoted text = %q..., want truncation", quoted[:20])
+				}
+				if strings.HasSuffix(kept, "\n>") || strings.LastIndexByte(kept, '&') > strings.LastIndexByte(kept, ';') {
+					t.Errorf("quoted text ends with a split sequence: %q", kept[len(kept)-10:])
+				}
+				if !strings.HasPrefix(quoteSlack(test.text, len(test.text)*5), kept) {
+					t.Errorf("quoted text is not a prefix of the whole quote")
+				}
+			}
+			if n := utf8.RuneCountInString(message.Text); n > slackMaxSectionText {
+				t.Errorf("fallback text has %d characters, want at most %d", n, slackMaxSectionText)
+			}
+		})
+	}
+}
+
+func TestFormatDiscord(t *testing.T) {
+	b := testBark("")
+	b.Idea.Text = strings.Repeat("吠", discordMaxEmbedDescText+1)
+	formatted, err := FormatDiscord(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embed := formatted.(*DiscordMessage).Embeds[0]
+
+	if n := utf8.RuneCountInString(embed.Description); n != discordMaxEmbedDescText || !strings.HasSuffix(embed.Description, "…") {
+		t.Errorf("description has %d characters, want %d ending in an ellipsis", n, discordMaxEmbedDescText)
+	}
+	if embed.Timestamp != "2024-01-01T09:00:00Z" {
+		t.Errorf("timestamp = %q, want scheduled time", embed.Timestamp)
+	}
+	if embed.Footer == nil || embed.Footer.Text != chatFooter(b) {
+		t.Errorf("footer = %+v, want chat footer", embed.Footer)
+	}
+
+	b.ScheduledTime = time.Time{}
+	formatted, _ = FormatDiscord(b)
+	if timestamp := formatted.(*DiscordMessage).Embeds[0].Timestamp; timestamp != "" {
+		t.Errorf("timestamp = %q without a scheduled time, want none", timestamp)
+	}
+}
+
+func TestChatFooter(t *testing.T) {
+	b := testBark("")
+	b.Dog.CreationTime = time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	if got, want := chatFooter(b), "cron: 0 9 * * * · dog created Mar 5, 2024"; got != want {
+		t.Errorf("chatFooter(bark) = %q, want %q", got, want)
+	}
+
+	b.DigestID = b.Dog.ID
+	b.Digest = []dog.DigestEntry{{IdeaText: "drink water"}, {IdeaText: "stretch"}}
+	if got, want := chatFooter(b), "digest of 2 · cron: 0 9 * * * · digest created Mar 5, 2024"; got != want {
+		t.Errorf("chatFooter(digest) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,222 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// Default email templates. Templates are executed with the *dog.Bark being delivered.
+var (
+	DefaultEmailSubjectTemplate = texttemplate.Must(texttemplate.New("subject").Parse(
+		`{{if .Digest}}Bark! Your digest of {{len .Digest}} thoughts` +
+			`{{else}}Bark! A thought from {{.Idea.CreationTime.Format "Jan 2, 2006"}}{{end}}`))
+
+	DefaultEmailTextTemplate = texttemplate.Must(texttemplate.New("text").Parse(
+		`{{if .Digest}}{{range .Digest}}* {{.IdeaText}}
+{{end}}{{else}}{{.Idea.Text}}
+
+--
+You had this idea on {{.Idea.CreationTime.Format "Monday, January 2, 2006"}}.
+{{end}}`))
+
+	DefaultEmailHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(
+		`<!DOCTYPE html>
+<html>
+<body>
+{{if .Digest}}<ul>
+{{range .Digest}}<li style="font-size: 1.2em;">{{.IdeaText}}</li>
+{{end}}</ul>
+{{else}}<blockquote style="font-size: 1.2em;">{{.Idea.Text}}</blockquote>
+<p style="color: #777;">You had this idea on {{.Idea.CreationTime.Format "Monday, January 2, 2006"}}.</p>
+{{end}}</body>
+</html>
+`))
+)
+
+// ErrNoEmailAddress is returned when an email bark has no recipient address.
+var ErrNoEmailAddress = errors.New("no email address for bark")
+
+// EmailBarker is a delivery channel that sends barks as email over SMTP.
+// The bark's address is used as the recipient.
+type EmailBarker struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+	// From is the sender address.
+	From string
+
+	// Username and Password are used for PLAIN authentication if Username is set.
+	Username string
+	Password string
+
+	// StartTLS upgrades the connection with STARTTLS before authenticating or sending.
+	StartTLS bool
+	// TLSConfig is the configuration used for STARTTLS. If nil, a default configuration for the
+	// server host is used.
+	TLSConfig *tls.Config
+
+	// Templates used to render each email. If nil, the default templates are used.
+	SubjectTemplate *texttemplate.Template
+	TextTemplate    *texttemplate.Template
+	HTMLTemplate    *htmltemplate.Template
+}
+
+// Bark implements the dog.Barker interface.
+func (barker *EmailBarker) Bark(ctx context.Context, b *dog.Bark) error {
+	if b.Address == "" {
+		return ErrNoEmailAddress
+	}
+	to, err := mail.ParseAddress(b.Address)
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(barker.From)
+	if err != nil {
+		return err
+	}
+
+	msg, err := barker.render(b, from, to)
+	if err != nil {
+		return err
+	}
+
+	return barker.send(ctx, from.Address, to.Address, msg)
+}
+
+// render builds a multipart/alternative message with plain text and HTML bodies.
+func (barker *EmailBarker) render(b *dog.Bark, from, to *mail.Address) ([]byte, error) {
+	subjectTemplate := barker.SubjectTemplate
+	if subjectTemplate == nil {
+		subjectTemplate = DefaultEmailSubjectTemplate
+	}
+	textTemplate := barker.TextTemplate
+	if textTemplate == nil {
+		textTemplate = DefaultEmailTextTemplate
+	}
+	htmlTemplate := barker.HTMLTemplate
+	if htmlTemplate == nil {
+		htmlTemplate = DefaultEmailHTMLTemplate
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := subjectTemplate.Execute(&subject, b); err != nil {
+		return nil, err
+	}
+	if err := textTemplate.Execute(&text, b); err != nil {
+		return nil, err
+	}
+	if err := htmlTemplate.Execute(&html, b); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject.String()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n", parts.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// send delivers a rendered message over an SMTP connection.
+func (barker *EmailBarker) send(ctx context.Context, from, to string, msg []byte) error {
+	host, _, err := net.SplitHostPort(barker.Addr)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", barker.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if barker.StartTLS {
+		tlsConfig := barker.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: host}
+		}
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if barker.Username != "" {
+		auth := smtp.PlainAuth("", barker.Username, barker.Password, host)
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Mail(from); err != nil {
+		return err
+	}
+	if err = c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,214 @@
+package delivery
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// smtpMessage is a message received by an smtpServer.
+type smtpMessage struct {
+	auth, from, to string
+	data           string
+}
+
+// smtpServer is a local stand-in for an SMTP server that accepts every message.
+type smtpServer struct {
+	listener net.Listener
+	messages chan smtpMessage
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &smtpServer{listener: listener, messages: make(chan smtpMessage, 1)}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (server *smtpServer) serve() {
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		go server.converse(textproto.NewConn(conn))
+	}
+}
+
+func (server *smtpServer) converse(conn *textproto.Conn) {
+	defer conn.Close()
+	var message smtpMessage
+
+	conn.PrintfLine("220 localhost ready")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		command, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			command, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			conn.PrintfLine("250-localhost")
+			conn.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			message.auth = string(credentials)
+			conn.PrintfLine("235 authenticated")
+		case "MAIL":
+			message.from = arg
+			conn.PrintfLine("250 OK")
+		case "RCPT":
+			message.to = arg
+			conn.PrintfLine("250 OK")
+		case "DATA":
+			conn.PrintfLine("354 send data")
+			data, err := io.ReadAll(conn.DotReader())
+			if err != nil {
+				return
+			}
+			message.data = string(data)
+			conn.PrintfLine("250 OK")
+			server.messages <- message
+		case "QUIT":
+			conn.PrintfLine("221 bye")
+			return
+		default:
+			conn.PrintfLine("502 unsupported")
+		}
+	}
+}
+
+// readEmailParts returns the decoded parts of a multipart message by content type.
+func readEmailParts(t *testing.T, data string) (*mail.Message, map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(part)
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[mediaType] = string(content)
+	}
+	return msg, parts
+}
+
+func TestEmailBarker(t *testing.T) {
+	ideaTime := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		bark        *dog.Bark
+		username    string
+		wantSubject string
+		wantText    []string
+	}{
+		{
+			name: "idea",
+			bark: &dog.Bark{
+				Dog:  &dog.Dog{ID: "dog1"},
+				Idea: &bark.Idea{ID: "idea1", Text: "café <au> lait", CreationTime: ideaTime},
+			},
+			wantSubject: "Bark! A thought from Mar 5, 2024",
+			wantText:    []string{"café <au> lait", "Tuesday, March 5, 2024"},
+		},
+		{
+			name: "digest with authentication",
+			bark: &dog.Bark{
+				Dog:  &dog.Dog{ID: "digest1"},
+				Idea: &bark.Idea{Text: "drink water\nstretch"},
+				Digest: []dog.DigestEntry{
+					{BarkID: "bark1", IdeaText: "drink water"},
+					{BarkID: "bark2", IdeaText: "stretch"},
+				},
+			},
+			username:    "bark",
+			wantSubject: "Bark! Your digest of 2 thoughts",
+			wantText:    []string{"* drink water", "* stretch"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newSMTPServer(t)
+			barker := &EmailBarker{
+				Addr:     server.listener.Addr().String(),
+				From:     "Bark <bark@example.com>",
+				Username: test.username,
+				Password: "s3cret",
+			}
+			test.bark.Address = "someone@example.com"
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := barker.Bark(ctx, test.bark); err != nil {
+				t.Fatal(err)
+			}
+			message := <-server.messages
+
+			if message.from != "FROM:<bark@example.com>" || !strings.HasPrefix(message.to, "TO:<someone@example.com>") {
+				t.Errorf("envelope = %s %s", message.from, message.to)
+			}
+			if wantAuth := "\x00bark\x00s3cret"; test.username != "" && message.auth != wantAuth {
+				t.Errorf("auth = %q, want %q", message.auth, wantAuth)
+			}
+
+			msg, parts := readEmailParts(t, message.data)
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil || subject != test.wantSubject {
+				t.Errorf("subject = %q, %v, want %q", subject, err, test.wantSubject)
+			}
+			for _, want := range test.wantText {
+				if !strings.Contains(parts["text/plain"], want) {
+					t.Errorf("text part = %q, want %q", parts["text/plain"], want)
+				}
+			}
+			if html := parts["text/html"]; test.bark.Digest == nil && !strings.Contains(html, "café &lt;au&gt; lait") {
+				t.Errorf("html part = %q, want escaped idea text", html)
+			}
+		})
+	}
+}
+
+func TestEmailBarkerErrors(t *testing.T) {
+	barker := &EmailBarker{Addr: "127.0.0.1:1", From: "bark@example.com"}
+	b := &dog.Bark{Dog: &dog.Dog{ID: "dog1"}, Idea: &bark.Idea{Text: "drink water"}}
+
+	if err := barker.Bark(context.Background(), b); err != ErrNoEmailAddress {
+		t.Errorf("Bark() without address = %v, want %v", err, ErrNoEmailAddress)
+	}
+	b.Address = "not an address"
+	if err := barker.Bark(context.Background(), b); err == nil {
+		t.Error("Bark() to invalid address succeeded, want error")
+	}
+}
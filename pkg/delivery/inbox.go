@@ -0,0 +1,39 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// ErrNoInboxUser is returned when an inbox bark has no user to deliver to.
+var ErrNoInboxUser = errors.New("no user ID for inbox bark")
+
+// InboxBarker is a delivery channel that places barks in a user's inbox.
+// The bark's address is used as the user ID, defaulting to the dog's user.
+type InboxBarker struct {
+	Inbox dog.InboxStore
+}
+
+// Bark implements the dog.Barker interface.
+func (barker *InboxBarker) Bark(ctx context.Context, b *dog.Bark) error {
+	userID := b.Address
+	if userID == "" {
+		userID = b.Dog.UserID
+	}
+	if userID == "" {
+		return ErrNoInboxUser
+	}
+
+	return barker.Inbox.Set(ctx, &dog.InboxItem{
+		ID:           b.ID,
+		UserID:       userID,
+		DogID:        b.Dog.ID,
+		IdeaID:       b.Idea.ID,
+		IdeaText:     b.Idea.Text,
+		State:        dog.InboxUnread,
+		ReceivedTime: time.Now(),
+	})
+}
@@ -0,0 +1,22 @@
+package delivery
+
+import (
+	"context"
+	"html"
+
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// LogBarker is a delivery channel that writes barks to a logger.
+type LogBarker struct {
+	Logger interface {
+		Printf(format string, v ...interface{})
+	}
+}
+
+// Bark implements the dog.Barker interface.
+func (barker *LogBarker) Bark(ctx context.Context, b *dog.Bark) error {
+	barker.Logger.Printf(`channel=log dogID=%s ideaID=%s ideaText="%s"`,
+		b.Dog.ID, b.Idea.ID, html.EscapeString(b.Idea.Text))
+	return nil
+}
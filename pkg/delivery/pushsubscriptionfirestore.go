@@ -0,0 +1,56 @@
+package delivery
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// PushSubscriptionFirestore is a Google Cloud Firestore-based data backend for push subscriptions.
+type PushSubscriptionFirestore struct {
+	FirestoreClient *firestore.Client
+}
+
+// Put inserts a push subscription. If there is an existing subscription with the same key, it
+// will be overwritten.
+func (store *PushSubscriptionFirestore) Put(ctx context.Context,
+	subscription *PushSubscription) error {
+
+	docID := "pushSubscriptions/" + subscription.ID
+	_, err := store.FirestoreClient.Doc(docID).Set(ctx, subscription)
+	return err
+}
+
+// ListByUser returns all push subscriptions for a user.
+func (store *PushSubscriptionFirestore) ListByUser(ctx context.Context,
+	userID string) ([]*PushSubscription, error) {
+
+	iter := store.FirestoreClient.Collection("pushSubscriptions").
+		Where("userId", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	var subscriptions []*PushSubscription
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var subscription PushSubscription
+		if err = doc.DataTo(&subscription); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, &subscription)
+	}
+	return subscriptions, nil
+}
+
+// Delete deletes a push subscription.
+func (store *PushSubscriptionFirestore) Delete(ctx context.Context, ID string) error {
+	docID := "pushSubscriptions/" + ID
+	_, err := store.FirestoreClient.Doc(docID).Delete(ctx)
+	return err
+}
@@ -0,0 +1,43 @@
+package delivery
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// Recorder is a delivery channel that records barks in memory instead of delivering them.
+// It is intended as a fake for testing delivery.
+type Recorder struct {
+	// Err, if set, is returned by every call to Bark. Failed barks are not recorded.
+	Err error
+
+	mu    sync.Mutex
+	barks []*dog.Bark
+}
+
+// Bark implements the dog.Barker interface.
+func (recorder *Recorder) Bark(ctx context.Context, b *dog.Bark) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if recorder.Err != nil {
+		return recorder.Err
+	}
+	recorder.barks = append(recorder.barks, b)
+	return nil
+}
+
+// Barks returns the barks recorded so far.
+func (recorder *Recorder) Barks() []*dog.Bark {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return append([]*dog.Bark(nil), recorder.barks...)
+}
+
+// Reset clears all recorded barks.
+func (recorder *Recorder) Reset() {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.barks = nil
+}
@@ -0,0 +1,301 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// Headers set on signed webhook requests.
+const (
+	WebhookTimestampHeader = "X-Bark-Timestamp"
+	WebhookSignatureHeader = "X-Bark-Signature"
+)
+
+// webhookSignaturePrefix identifies the signature scheme in the signature header.
+const webhookSignaturePrefix = "v1="
+
+// Errors returned by VerifyWebhookSignature.
+var (
+	ErrWebhookSignatureMissing = errors.New("webhook signature missing")
+	ErrWebhookSignatureInvalid = errors.New("webhook signature invalid")
+	ErrWebhookTimestampExpired = errors.New("webhook timestamp outside of tolerance")
+)
+
+// Errors returned by WebhookBarker.
+var (
+	ErrNoWebhookURL         = errors.New("no webhook URL for bark")
+	ErrInvalidWebhookURL    = errors.New("webhook URL must be an absolute http or https URL")
+	ErrWebhookSecretMissing = errors.New("webhook delivery has no secret")
+)
+
+// ErrAddressNotAllowed is returned by clients from NewPublicClient when connecting to an address
+// that is not public.
+var ErrAddressNotAllowed = errors.New("connections to loopback, private and link-local addresses are not allowed")
+
+// nonPublicNetworks are the networks to which clients from NewPublicClient do not connect.
+var nonPublicNetworks = parseCIDRs(
+	"0.0.0.0/8",      // this network
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // carrier-grade NAT
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link-local, including cloud metadata servers
+	"172.16.0.0/12",  // private
+	"192.168.0.0/16", // private
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved and broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link-local
+	"ff00::/8",       // multicast
+)
+
+// Webhook payload types, which distinguish the payloads of single barks from those of digests.
+const (
+	WebhookPayloadBark   = "bark"
+	WebhookPayloadDigest = "digest"
+)
+
+// WebhookPayload is the JSON body posted by the webhook channel for the bark of a single idea.
+type WebhookPayload struct {
+	Type          string    `json:"type"`
+	DogID         string    `json:"dogId"`
+	IdeaID        string    `json:"ideaId"`
+	IdeaText      string    `json:"ideaText"`
+	ScheduledTime time.Time `json:"scheduledTime"`
+	Attempt       int       `json:"attempt"`
+}
+
+// DigestWebhookPayload is the JSON body posted by the webhook channel for a digest bark.
+type DigestWebhookPayload struct {
+	Type          string            `json:"type"`
+	DigestID      string            `json:"digestId"`
+	ScheduledTime time.Time         `json:"scheduledTime"`
+	Attempt       int               `json:"attempt"`
+	Entries       []dog.DigestEntry `json:"entries"`
+}
+
+// A WebhookFormatter converts a bark into the value posted as JSON by a WebhookBarker.
+type WebhookFormatter func(b *dog.Bark) (interface{}, error)
+
+// FormatWebhookPayload is the default WebhookFormatter. It formats a digest bark as a
+// DigestWebhookPayload, and any other bark as a WebhookPayload.
+func FormatWebhookPayload(b *dog.Bark) (interface{}, error) {
+	if b.Digest != nil {
+		return &DigestWebhookPayload{
+			Type:          WebhookPayloadDigest,
+			DigestID:      b.DigestID,
+			ScheduledTime: b.ScheduledTime,
+			Attempt:       b.Attempt,
+			Entries:       b.Digest,
+		}, nil
+	}
+	return &WebhookPayload{
+		Type:          WebhookPayloadBark,
+		DogID:         b.Dog.ID,
+		IdeaID:        b.Idea.ID,
+		IdeaText:      b.Idea.Text,
+		ScheduledTime: b.ScheduledTime,
+		Attempt:       b.Attempt,
+	}, nil
+}
+
+// WebhookBarker is a delivery channel that posts barks as JSON to a URL.
+// The bark's address is used as the URL. The posted value is produced by Format, which
+// defaults to FormatWebhookPayload.
+//
+// Each request is signed with an HMAC-SHA256 of its timestamp and body using the secret of the
+// bark's delivery, which is generated when the delivery is created. The
+// timestamp is sent in the X-Bark-Timestamp header as Unix seconds and the signature is sent in
+// the X-Bark-Signature header as "v1=" followed by the hex-encoded HMAC of "<timestamp>.<body>".
+// Barks fail without a secret, unless Unsigned is set for services that do not verify signatures,
+// such as Slack and Discord.
+//
+// Since URLs are supplied by users, Client should refuse non-public addresses, as clients from
+// NewPublicClient do. If Client is nil, a client from NewPublicClient is used.
+type WebhookBarker struct {
+	Format   WebhookFormatter
+	Unsigned bool
+	Client   *http.Client
+}
+
+// defaultWebhookClient is the client of WebhookBarkers without a client.
+var defaultWebhookClient = NewPublicClient(10 * time.Second)
+
+// Bark implements the dog.Barker interface.
+func (barker *WebhookBarker) Bark(ctx context.Context, b *dog.Bark) error {
+	if b.Address == "" {
+		return ErrNoWebhookURL
+	}
+	if u, err := url.Parse(b.Address); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	var secret []byte
+	if !barker.Unsigned {
+		if b.Secret == "" {
+			return ErrWebhookSecretMissing
+		}
+		secret = []byte(b.Secret)
+	}
+
+	format := barker.Format
+	if format == nil {
+		format = FormatWebhookPayload
+	}
+	payload, err := format(b)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return err
+	}
+
+	return barker.post(ctx, b.Address, secret, body.Bytes())
+}
+
+// SignsBarks implements the dog.SigningBarker interface.
+func (barker *WebhookBarker) SignsBarks() bool {
+	return !barker.Unsigned
+}
+
+// post sends a JSON body to a URL, signed with secret unless it is empty.
+func (barker *WebhookBarker) post(ctx context.Context, address string, secret, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", bark.ContentTypeJSON)
+	if len(secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader,
+			webhookSignaturePrefix+signWebhook(secret, timestamp, body))
+	}
+
+	client := barker.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signWebhook returns the hex-encoded HMAC-SHA256 of a webhook timestamp and body.
+func signWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature verifies the signature headers of a webhook request sent by a
+// WebhookBarker. Requests with a timestamp further than tolerance from the current time are
+// rejected to prevent replays.
+func VerifyWebhookSignature(secret []byte, header http.Header, body []byte,
+	tolerance time.Duration) error {
+
+	timestamp := header.Get(WebhookTimestampHeader)
+	signature := header.Get(WebhookSignatureHeader)
+	if timestamp == "" || signature == "" {
+		return ErrWebhookSignatureMissing
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrWebhookSignatureInvalid
+	}
+	age := time.Since(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrWebhookTimestampExpired
+	}
+
+	if !strings.HasPrefix(signature, webhookSignaturePrefix) {
+		return ErrWebhookSignatureInvalid
+	}
+	expected := signWebhook(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimPrefix(signature, webhookSignaturePrefix))) {
+		return ErrWebhookSignatureInvalid
+	}
+	return nil
+}
+
+// NewPublicClient returns an HTTP client for requests to user-supplied URLs. The client refuses
+// to connect to loopback, private, link-local and other non-public addresses, including through
+// redirects and DNS names that resolve to such addresses.
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicIP(net.ParseIP(host)) {
+				return ErrAddressNotAllowed
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// isPublicIP reports whether ip is a public address.
+func isPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCIDRs parses CIDR notation networks, panicking if any is invalid.
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
@@ -0,0 +1,187 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+func testBark(address string) *dog.Bark {
+	return &dog.Bark{
+		ID:            "bark1",
+		Dog:           &dog.Dog{ID: "dog1", ScheduleType: "cron", ScheduleRaw: json.RawMessage(`"0 9 * * *"`)},
+		Idea:          &bark.Idea{ID: "idea1", Text: "drink water"},
+		Address:       address,
+		Secret:        "s3cret",
+		ScheduledTime: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		Attempt:       1,
+	}
+}
+
+func TestWebhookBarkerSigned(t *testing.T) {
+	secret := []byte("s3cret")
+	var payload WebhookPayload
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = VerifyWebhookSignature(secret, r.Header, body, time.Minute)
+		json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	barker := &WebhookBarker{Client: server.Client()}
+	if err := barker.Bark(context.Background(), testBark(server.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if verifyErr != nil {
+		t.Errorf("VerifyWebhookSignature() = %v", verifyErr)
+	}
+	if payload.DogID != "dog1" || payload.IdeaText != "drink water" || payload.Attempt != 1 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestFormatWebhookPayload(t *testing.T) {
+	single, _ := FormatWebhookPayload(testBark(""))
+	if payload, ok := single.(*WebhookPayload); !ok || payload.Type != WebhookPayloadBark || payload.DogID != "dog1" {
+		t.Errorf("FormatWebhookPayload(bark) = %+v", single)
+	}
+
+	digestBark := testBark("")
+	digestBark.Dog.ID = "digest1"
+	digestBark.DigestID = "digest1"
+	digestBark.Digest = []dog.DigestEntry{
+		{BarkID: "bark1", DogID: "dog1", IdeaID: "idea1", IdeaText: "drink water"},
+		{BarkID: "bark2", DogID: "dog2", IdeaID: "idea2", IdeaText: "stretch"},
+	}
+	digest, _ := FormatWebhookPayload(digestBark)
+	b, err := json.Marshal(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	json.Unmarshal(b, &fields)
+	if string(fields["type"]) != `"digest"` || string(fields["digestId"]) != `"digest1"` {
+		t.Errorf("digest payload = %s, want type and digest ID", b)
+	}
+	if _, found := fields["dogId"]; found {
+		t.Errorf("digest payload = %s, want no dog ID", b)
+	}
+	if payload := digest.(*DigestWebhookPayload); len(payload.Entries) != 2 {
+		t.Errorf("digest payload has %d entries, want 2", len(payload.Entries))
+	}
+}
+
+func TestWebhookBarkerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(WebhookSignatureHeader) != "" {
+			t.Error("unsigned barker signed request")
+		}
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		barker  *WebhookBarker
+		address string
+		secret  string
+		wantErr error
+	}{
+		{"no address", &WebhookBarker{}, "", "s3cret", ErrNoWebhookURL},
+		{"relative address", &WebhookBarker{}, "/hook", "s3cret", ErrInvalidWebhookURL},
+		{"other scheme", &WebhookBarker{}, "file:///etc/passwd", "s3cret", ErrInvalidWebhookURL},
+		{"no secret", &WebhookBarker{Client: server.Client()}, server.URL, "", ErrWebhookSecretMissing},
+		{"loopback", &WebhookBarker{}, server.URL, "s3cret", ErrAddressNotAllowed},
+		{"metadata server", &WebhookBarker{}, "http://169.254.169.254/computeMetadata/v1/", "s3cret", ErrAddressNotAllowed},
+		{"error status", &WebhookBarker{Unsigned: true, Client: server.Client()}, server.URL, "s3cret", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := testBark(test.address)
+			b.Secret = test.secret
+			err := test.barker.Bark(context.Background(), b)
+			if err == nil {
+				t.Fatal("Bark() succeeded, want error")
+			}
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("Bark() = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"dogId":"dog1"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	header := func(timestamp, signature string) http.Header {
+		h := http.Header{}
+		if timestamp != "" {
+			h.Set(WebhookTimestampHeader, timestamp)
+		}
+		if signature != "" {
+			h.Set(WebhookSignatureHeader, signature)
+		}
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"valid", header(now, "v1="+signWebhook(secret, now, body)), body, nil},
+		{"missing signature", header(now, ""), body, ErrWebhookSignatureMissing},
+		{"missing timestamp", header("", "v1="+signWebhook(secret, now, body)), body, ErrWebhookSignatureMissing},
+		{"malformed timestamp", header("noon", "v1=00"), body, ErrWebhookSignatureInvalid},
+		{"stale timestamp", header(stale, "v1="+signWebhook(secret, stale, body)), body, ErrWebhookTimestampExpired},
+		{"unknown scheme", header(now, "v0="+signWebhook(secret, now, body)), body, ErrWebhookSignatureInvalid},
+		{"other secret", header(now, "v1="+signWebhook([]byte("guess"), now, body)), body, ErrWebhookSignatureInvalid},
+		{"modified body", header(now, "v1="+signWebhook(secret, now, body)), []byte(`{"dogId":"dog2"}`), ErrWebhookSignatureInvalid},
+	}
+
+	for _, test := range tests {
+		if err := VerifyWebhookSignature(secret, test.header, test.body, 5*time.Minute); err != test.want {
+			t.Errorf("%s: VerifyWebhookSignature() = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for address, want := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::1":   true,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"172.31.255.255":       false,
+		"192.168.0.1":          false,
+		"169.254.169.254":      false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"::1":                  false,
+		"::":                   false,
+		"fd00::1":              false,
+		"fe80::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.216.34": true,
+	} {
+		if got := isPublicIP(net.ParseIP(address)); got != want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
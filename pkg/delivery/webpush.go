@@ -0,0 +1,366 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// webPushRecordSize is the aes128gcm record size advertised in encrypted push messages.
+const webPushRecordSize = 4096
+
+// webPushMaxPayload is the largest encrypted push message body, header included, that push
+// services must accept per RFC 8291.
+const webPushMaxPayload = 4096
+
+// webPushHeaderSize is the size of the aes128gcm header of a push message: the salt, record size,
+// key ID length and the application server's uncompressed public key.
+const webPushHeaderSize = 16 + 4 + 1 + 65
+
+// webPushMaxMessage is the largest push message that fits in webPushMaxPayload once encrypted,
+// leaving room for the header, the padding delimiter and the 16 byte authentication tag.
+const webPushMaxMessage = webPushMaxPayload - webPushHeaderSize - 1 - 16
+
+// errPushMessageTooLarge is returned when a push message does not fit in a push payload.
+var errPushMessageTooLarge = errors.New("push message too large")
+
+// vapidTokenLifetime is the lifetime of VAPID tokens. RFC 8292 limits it to 24 hours.
+const vapidTokenLifetime = 12 * time.Hour
+
+// ErrNoPushUser is returned when a web push bark has no user ID address.
+var ErrNoPushUser = errors.New("no user ID for web push bark")
+
+// ErrNoPushSubscriptions is returned when a web push bark's user has no subscriptions.
+var ErrNoPushSubscriptions = errors.New("no push subscriptions for user")
+
+// ErrPushSubscriptionInvalid is returned when a push subscription's keys cannot be decoded.
+var ErrPushSubscriptionInvalid = errors.New("push subscription invalid")
+
+// PushSubscription is a browser PushSubscription registered for a user.
+type PushSubscription struct {
+	ID           string               `json:"id" firestore:"id"`
+	UserID       string               `json:"userId" firestore:"userId"`
+	Endpoint     string               `json:"endpoint" firestore:"endpoint"`
+	Keys         PushSubscriptionKeys `json:"keys" firestore:"keys"`
+	CreationTime time.Time            `json:"creationTime" firestore:"creationTime"`
+}
+
+// PushSubscriptionKeys are the base64url-encoded keys of a browser PushSubscription.
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh" firestore:"p256dh"`
+	Auth   string `json:"auth" firestore:"auth"`
+}
+
+// PushSubscriptionStore is a data store for push subscriptions.
+type PushSubscriptionStore interface {
+	Put(ctx context.Context, subscription *PushSubscription) error
+	ListByUser(ctx context.Context, userID string) ([]*PushSubscription, error)
+	Delete(ctx context.Context, ID string) error
+}
+
+// PushMessage is the JSON payload delivered to a browser's service worker.
+type PushMessage struct {
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	DogID  string `json:"dogId"`
+	IdeaID string `json:"ideaId"`
+}
+
+// VAPIDKeys identify an application server to push services per RFC 8292.
+type VAPIDKeys struct {
+	PrivateKey *ecdsa.PrivateKey
+	// Subject is a contact URI for the application server, e.g. "mailto:admin@example.com".
+	Subject string
+}
+
+// ParseVAPIDKeys returns VAPID keys from a base64url-encoded P-256 private key.
+func ParseVAPIDKeys(privateKey, subject string) (*VAPIDKeys, error) {
+	d, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(d) != 32 {
+		return nil, fmt.Errorf("VAPID private key must be 32 bytes, got %d", len(d))
+	}
+
+	curve := elliptic.P256()
+	key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	key.Curve = curve
+	key.X, key.Y = curve.ScalarBaseMult(d)
+
+	return &VAPIDKeys{
+		PrivateKey: key,
+		Subject:    subject,
+	}, nil
+}
+
+// PublicKey returns the base64url-encoded uncompressed public key, as used for the
+// applicationServerKey of browser subscriptions.
+func (keys *VAPIDKeys) PublicKey() string {
+	pub := elliptic.Marshal(keys.PrivateKey.Curve, keys.PrivateKey.X, keys.PrivateKey.Y)
+	return base64.RawURLEncoding.EncodeToString(pub)
+}
+
+// authorization returns the VAPID Authorization header value for a push endpoint.
+func (keys *VAPIDKeys) authorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenLifetime).Unix(),
+		"sub": keys.Subject,
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, keys.PrivateKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, keys.PublicKey()), nil
+}
+
+// WebPushBarker is a delivery channel that sends barks as browser push notifications.
+// The bark's address is used as the user ID, and the bark is sent to each of the user's
+// subscriptions. Subscriptions that the push service reports as gone are deleted.
+type WebPushBarker struct {
+	VAPID         *VAPIDKeys
+	Subscriptions PushSubscriptionStore
+	Client        *http.Client
+	// TTL is how long the push service should retain undelivered messages.
+	TTL time.Duration
+}
+
+// Bark implements the dog.Barker interface.
+func (barker *WebPushBarker) Bark(ctx context.Context, b *dog.Bark) error {
+	if b.Address == "" {
+		return ErrNoPushUser
+	}
+
+	subscriptions, err := barker.Subscriptions.ListByUser(ctx, b.Address)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return ErrNoPushSubscriptions
+	}
+
+	message, err := encodePushMessage(&PushMessage{
+		Title:  "Bark!",
+		Body:   b.Idea.Text,
+		DogID:  b.Dog.ID,
+		IdeaID: b.Idea.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// succeed if any subscription receives the bark
+	var lastErr error
+	delivered := false
+	for _, subscription := range subscriptions {
+		gone, err := barker.send(ctx, subscription, message)
+		if gone {
+			barker.Subscriptions.Delete(ctx, subscription.ID)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		delivered = true
+	}
+	if !delivered {
+		return lastErr
+	}
+	return nil
+}
+
+// encodePushMessage encodes a push message as JSON, truncating its body so that the encoded
+// message is at most webPushMaxMessage bytes. Truncation is marked with an ellipsis.
+func encodePushMessage(message *PushMessage) ([]byte, error) {
+	text := message.Body
+	for {
+		encoded, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		excess := len(encoded) - webPushMaxMessage
+		if excess <= 0 {
+			return encoded, nil
+		}
+		if text == "" {
+			return nil, errPushMessageTooLarge
+		}
+
+		// each byte of text encodes to at least one byte, so cutting the excess and room for the
+		// ellipsis from the text is enough, unless the message has nothing left to cut
+		n := len(text) - excess - len("…")
+		if n < 0 {
+			n = 0
+		}
+		for n > 0 && !utf8.RuneStart(text[n]) {
+			n--
+		}
+		text = text[:n]
+		message.Body = text + "…"
+	}
+}
+
+// send encrypts and sends a message to a single subscription. It reports whether the push service
+// indicated that the subscription no longer exists.
+func (barker *WebPushBarker) send(ctx context.Context, subscription *PushSubscription,
+	message []byte) (gone bool, err error) {
+
+	body, err := encryptPushMessage(subscription.Keys, message)
+	if err != nil {
+		return false, err
+	}
+	authorization, err := barker.VAPID.authorization(subscription.Endpoint)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint,
+		bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(barker.TTL.Seconds())))
+
+	client := barker.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return true, fmt.Errorf("push subscription gone with status %d", resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return false, fmt.Errorf("push service responded with status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// encryptPushMessage encrypts a message for a subscription using the aes128gcm content coding
+// as specified by RFC 8291.
+func encryptPushMessage(keys PushSubscriptionKeys, message []byte) ([]byte, error) {
+	uaPublic, err := base64.RawURLEncoding.DecodeString(trimPadding(keys.P256dh))
+	if err != nil {
+		return nil, ErrPushSubscriptionInvalid
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(trimPadding(keys.Auth))
+	if err != nil {
+		return nil, ErrPushSubscriptionInvalid
+	}
+
+	curve := elliptic.P256()
+	uaX, uaY := elliptic.Unmarshal(curve, uaPublic)
+	if uaX == nil {
+		return nil, ErrPushSubscriptionInvalid
+	}
+
+	// generate ephemeral application server key pair and shared secret
+	asPrivate, asX, asY, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := elliptic.Marshal(curve, asX, asY)
+	sharedX, _ := curve.ScalarMult(uaX, uaY, asPrivate)
+	ecdhSecret := make([]byte, 32)
+	sharedX.FillBytes(ecdhSecret)
+
+	// derive input keying material from the auth secret
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdf(authSecret, ecdhSecret, keyInfo, 32)
+
+	// derive content encryption key and nonce
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// single record terminated by the last-record padding delimiter
+	plaintext := append(append([]byte(nil), message...), 0x02)
+	if webPushHeaderSize+len(plaintext)+gcm.Overhead() > webPushMaxPayload {
+		return nil, errPushMessageTooLarge
+	}
+
+	// header: salt || record size || key ID length || key ID
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = append(header, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(header[16:], webPushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// hkdf derives length bytes (at most 32) using HKDF-SHA256 as specified by RFC 5869.
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
+
+// trimPadding removes base64 padding, which some browsers include in subscription keys.
+func trimPadding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
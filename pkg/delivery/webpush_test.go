@@ -0,0 +1,272 @@
+package delivery
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// pushServer is a local stand-in for a browser push service. It decrypts the messages sent to a
+// single subscription, as the browser would.
+type pushServer struct {
+	*httptest.Server
+	subscription *PushSubscription
+	uaPrivate    []byte
+	uaPublic     []byte
+	authSecret   []byte
+
+	// status is the status with which to respond to push requests
+	status int
+
+	mu       sync.Mutex
+	messages []PushMessage
+	bodySize []int
+	headers  []http.Header
+	errors   []error
+}
+
+func newPushServer(t *testing.T) *pushServer {
+	curve := elliptic.P256()
+	uaPrivate, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+
+	server := &pushServer{
+		uaPrivate:  uaPrivate,
+		uaPublic:   elliptic.Marshal(curve, x, y),
+		authSecret: authSecret,
+		status:     http.StatusCreated,
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.receive))
+	t.Cleanup(server.Close)
+
+	server.subscription = &PushSubscription{
+		ID:       "sub1",
+		UserID:   "user1",
+		Endpoint: server.URL + "/push/sub1",
+		Keys: PushSubscriptionKeys{
+			P256dh: base64.URLEncoding.EncodeToString(server.uaPublic),
+			Auth:   base64.RawURLEncoding.EncodeToString(authSecret),
+		},
+	}
+	return server
+}
+
+func (server *pushServer) receive(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	message, err := server.decrypt(body)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.headers = append(server.headers, r.Header)
+	server.bodySize = append(server.bodySize, len(body))
+	if err != nil {
+		server.errors = append(server.errors, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	server.messages = append(server.messages, message)
+	w.WriteHeader(server.status)
+}
+
+// decrypt decrypts an aes128gcm push message body as specified by RFC 8291.
+func (server *pushServer) decrypt(body []byte) (PushMessage, error) {
+	var message PushMessage
+	if len(body) < 21 {
+		return message, fmt.Errorf("body too short: %d bytes", len(body))
+	}
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	keyIDLen := int(body[20])
+	asPublic := body[21 : 21+keyIDLen]
+	ciphertext := body[21+keyIDLen:]
+	if int(recordSize) < len(ciphertext) {
+		return message, fmt.Errorf("record of %d bytes exceeds record size %d", len(ciphertext), recordSize)
+	}
+
+	curve := elliptic.P256()
+	asX, asY := elliptic.Unmarshal(curve, asPublic)
+	if asX == nil {
+		return message, fmt.Errorf("invalid key ID")
+	}
+	sharedX, _ := curve.ScalarMult(asX, asY, server.uaPrivate)
+	ecdhSecret := make([]byte, 32)
+	sharedX.FillBytes(ecdhSecret)
+
+	keyInfo := append([]byte("WebPush: info\x00"), server.uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdf(server.authSecret, ecdhSecret, keyInfo, 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return message, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return message, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return message, err
+	}
+
+	// strip padding up to the last-record delimiter
+	end := strings.LastIndexByte(string(plaintext), 0x02)
+	if end < 0 {
+		return message, fmt.Errorf("missing padding delimiter")
+	}
+	err = json.Unmarshal(plaintext[:end], &message)
+	return message, err
+}
+
+// memorySubscriptions is an in-memory PushSubscriptionStore.
+type memorySubscriptions struct {
+	subscriptions map[string]*PushSubscription
+}
+
+func (store *memorySubscriptions) Put(ctx context.Context, subscription *PushSubscription) error {
+	store.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (store *memorySubscriptions) ListByUser(ctx context.Context, userID string) ([]*PushSubscription, error) {
+	var subscriptions []*PushSubscription
+	for _, subscription := range store.subscriptions {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (store *memorySubscriptions) Delete(ctx context.Context, ID string) error {
+	delete(store.subscriptions, ID)
+	return nil
+}
+
+func newTestVAPIDKeys(t *testing.T) *VAPIDKeys {
+	d, _, _, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseVAPIDKeys(base64.RawURLEncoding.EncodeToString(d), "mailto:admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestWebPushBarker(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "short text", text: "drink water"},
+		{name: "long ASCII text", text: strings.Repeat("bark ", 1000)},
+		{name: "long CJK text", text: strings.Repeat("吠える", 1500)},
+		{name: "long emoji text", text: strings.Repeat("🐕", 2000)},
+		{name: "long escaped text", text: strings.Repeat("<&>", 2000)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newPushServer(t)
+			barker := &WebPushBarker{
+				VAPID: newTestVAPIDKeys(t),
+				Subscriptions: &memorySubscriptions{
+					subscriptions: map[string]*PushSubscription{"sub1": server.subscription},
+				},
+				Client: server.Client(),
+				TTL:    time.Hour,
+			}
+
+			err := barker.Bark(context.Background(), &dog.Bark{
+				Dog:     &dog.Dog{ID: "dog1"},
+				Idea:    &bark.Idea{ID: "idea1", Text: test.text},
+				Address: "user1",
+			})
+			if err != nil {
+				t.Fatalf("Bark() = %v, push service errors: %v", err, server.errors)
+			}
+
+			message := server.messages[0]
+			if message.DogID != "dog1" || message.IdeaID != "idea1" {
+				t.Errorf("message = %+v, want dog and idea IDs", message)
+			}
+			if size := server.bodySize[0]; size > 4096 {
+				t.Errorf("body size = %d, want at most 4096", size)
+			}
+			if !utf8.ValidString(message.Body) {
+				t.Errorf("body is not valid UTF-8: %q", message.Body)
+			}
+			if message.Body != test.text &&
+				!strings.HasPrefix(test.text, strings.TrimSuffix(message.Body, "…")) {
+				t.Errorf("body = %q, want a prefix of the idea text", message.Body)
+			}
+
+			header := server.headers[0]
+			if got := header.Get("Authorization"); !strings.HasPrefix(got, "vapid t=") {
+				t.Errorf("Authorization = %q, want VAPID token", got)
+			}
+			if got := header.Get("TTL"); got != "3600" {
+				t.Errorf("TTL = %q, want 3600", got)
+			}
+		})
+	}
+}
+
+func TestWebPushBarkerGoneSubscription(t *testing.T) {
+	server := newPushServer(t)
+	server.status = http.StatusGone
+	subscriptions := &memorySubscriptions{
+		subscriptions: map[string]*PushSubscription{"sub1": server.subscription},
+	}
+	barker := &WebPushBarker{
+		VAPID:         newTestVAPIDKeys(t),
+		Subscriptions: subscriptions,
+		Client:        server.Client(),
+	}
+
+	err := barker.Bark(context.Background(), &dog.Bark{
+		Dog:     &dog.Dog{ID: "dog1"},
+		Idea:    &bark.Idea{ID: "idea1", Text: "drink water"},
+		Address: "user1",
+	})
+	if err == nil {
+		t.Error("Bark() succeeded, want error for gone subscription")
+	}
+	if len(subscriptions.subscriptions) != 0 {
+		t.Error("gone subscription was not deleted")
+	}
+
+	err = barker.Bark(context.Background(), &dog.Bark{
+		Dog:     &dog.Dog{ID: "dog1"},
+		Idea:    &bark.Idea{ID: "idea1", Text: "drink water"},
+		Address: "user1",
+	})
+	if err != ErrNoPushSubscriptions {
+		t.Errorf("Bark() = %v, want %v", err, ErrNoPushSubscriptions)
+	}
+}
@@ -0,0 +1,127 @@
+package delivery
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+)
+
+var maxPushSubscriptionRequestSizeBytes int64 = 8000
+
+// WebPushService contains handlers for the web push subscription endpoints.
+type WebPushService struct {
+	bark.Service
+	VAPID         *VAPIDKeys
+	Subscriptions PushSubscriptionStore
+}
+
+// RegisterRoutes registers service routes to a chi router.
+func (service *WebPushService) RegisterRoutes(r chi.Router) {
+	r.Get("/push/vapid-public-key", service.GetVAPIDPublicKey)
+	r.Post("/users/{userID}/push-subscriptions", service.PostPushSubscription)
+}
+
+// VAPIDPublicKeyResponse is the response type for getting the VAPID public key.
+type VAPIDPublicKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// GetVAPIDPublicKey is a handler for getting the application server key that browsers must use
+// when subscribing.
+func (service *WebPushService) GetVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	service.Logf(r, "result=OK")
+	bark.RespondSuccess(w, http.StatusOK, &VAPIDPublicKeyResponse{
+		PublicKey: service.VAPID.PublicKey(),
+	})
+}
+
+// CreatePushSubscriptionRequest is the request type for registering a browser PushSubscription.
+// It matches the JSON serialization of a browser PushSubscription.
+type CreatePushSubscriptionRequest struct {
+	Endpoint string               `json:"endpoint"`
+	Keys     PushSubscriptionKeys `json:"keys"`
+}
+
+// PostPushSubscription is a handler for registering a browser PushSubscription for a user.
+func (service *WebPushService) PostPushSubscription(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	var requestBody CreatePushSubscriptionRequest
+
+	// read request body into struct
+	r.Body = http.MaxBytesReader(w, r.Body, maxPushSubscriptionRequestSizeBytes)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		service.Logf(r, `result=DecodeError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err = validatePushSubscription(&requestBody); err != nil {
+		service.Logf(r, `result=InvalidSubscriptionError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// key subscriptions by endpoint so that re-subscribing replaces the existing subscription
+	endpointHash := sha256.Sum256([]byte(requestBody.Endpoint))
+	subscription := &PushSubscription{
+		ID:           hex.EncodeToString(endpointHash[:]),
+		UserID:       userID,
+		Endpoint:     requestBody.Endpoint,
+		Keys:         requestBody.Keys,
+		CreationTime: time.Now(),
+	}
+
+	err = service.Subscriptions.Put(r.Context(), subscription)
+	if err != nil {
+		service.Logf(r, `action=PutPushSubscription subscriptionID=%s result=InternalError errorText="%s"`,
+			subscription.ID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	service.Logf(r, `action=PutPushSubscription subscriptionID=%s result=OK`, subscription.ID)
+
+	bark.RespondSuccess(w, http.StatusCreated, subscription)
+}
+
+// validatePushSubscription verifies that a subscription has an HTTPS endpoint and usable keys.
+// Plain HTTP endpoints are only allowed on the loopback interface.
+func validatePushSubscription(request *CreatePushSubscriptionRequest) error {
+	u, err := url.Parse(request.Endpoint)
+	if err != nil || u.Host == "" {
+		return ErrPushSubscriptionInvalid
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && isLoopback(u.Hostname())) {
+		return ErrPushSubscriptionInvalid
+	}
+	p256dh, err := base64.RawURLEncoding.DecodeString(trimPadding(request.Keys.P256dh))
+	if err != nil {
+		return ErrPushSubscriptionInvalid
+	}
+	if x, _ := elliptic.Unmarshal(elliptic.P256(), p256dh); x == nil {
+		return ErrPushSubscriptionInvalid
+	}
+	auth, err := base64.RawURLEncoding.DecodeString(trimPadding(request.Keys.Auth))
+	if err != nil || len(auth) != 16 {
+		return ErrPushSubscriptionInvalid
+	}
+	return nil
+}
+
+// isLoopback reports whether host names the loopback interface.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,37 @@
+package dog
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AtSchedule is a schedule that fires once at a fixed time.
+type AtSchedule struct {
+	Time time.Time
+}
+
+func init() {
+	RegisterScheduleType("at", func(b json.RawMessage) (Schedule, error) {
+		var schedule AtSchedule
+		err := json.Unmarshal(b, &schedule)
+		return schedule, err
+	})
+}
+
+// Next implements the Schedule interface.
+func (s AtSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.Time) {
+		return s.Time
+	}
+	return time.Time{}
+}
+
+// UnmarshalJSON parses an RFC 3339 JSON string into an AtSchedule.
+func (s *AtSchedule) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &s.Time)
+}
+
+// MarshalJSON writes the time as an RFC 3339 JSON string.
+func (s AtSchedule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Time)
+}
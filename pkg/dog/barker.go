@@ -0,0 +1,170 @@
+package dog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+)
+
+// A Delivery routes a Dog's barks to a delivery channel.
+type Delivery struct {
+	Channel string `json:"channel" firestore:"channel"`
+	Address string `json:"address,omitempty" firestore:"address,omitempty"`
+	// Secret signs the delivery's barks on channels whose barkers sign barks. It is generated when
+	// the delivery is created and is only ever returned in the creation response.
+	Secret string `json:"-" firestore:"secret,omitempty"`
+}
+
+// A DeliverySecret is the secret generated for a delivery, as returned when the delivery is
+// created.
+type DeliverySecret struct {
+	Channel string `json:"channel"`
+	Address string `json:"address,omitempty"`
+	Secret  string `json:"secret"`
+}
+
+// A Bark is a single delivery of a Dog's Idea.
+type Bark struct {
+	// ID uniquely identifies the bark. It is also the ID of the bark's history record.
+	ID      string
+	Dog     *Dog
+	Idea    *bark.Idea
+	Address string
+	// Secret is the secret of the bark's delivery, if any.
+	Secret string
+
+	// TaskName is the name of the task that triggered the bark.
+	TaskName string
+	// ScheduledTime is the time the bark was scheduled for.
+	ScheduledTime time.Time
+	// Attempt is the delivery attempt number, starting at 1.
+	Attempt int
+
+	// DigestID and Digest hold the ID and entries of a digest bark, in which case Dog describes the
+	// digest and Idea holds the combined text of its entries. Digest is nil for barks of a single
+	// idea.
+	DigestID string
+	Digest   []DigestEntry
+}
+
+// A Barker delivers barks to a delivery channel.
+type Barker interface {
+	Bark(ctx context.Context, b *Bark) error
+}
+
+// BarkerFunc is an adapter to allow the use of ordinary functions as Barkers.
+type BarkerFunc func(ctx context.Context, b *Bark) error
+
+// Bark calls f(ctx, b).
+func (f BarkerFunc) Bark(ctx context.Context, b *Bark) error {
+	return f(ctx, b)
+}
+
+// A SigningBarker is a Barker that signs barks with the secret of their delivery.
+type SigningBarker interface {
+	Barker
+	// SignsBarks reports whether the barker signs barks and so needs a secret for each delivery.
+	SignsBarks() bool
+}
+
+// ErrChannelNotRegistered is returned when a delivery references a channel that has no
+// registered Barker.
+var ErrChannelNotRegistered = errors.New("delivery channel not registered")
+
+// BarkerRegistry is a registry of Barkers by channel name.
+//
+// Dogs without any deliveries are delivered to DefaultChannel, if set.
+type BarkerRegistry struct {
+	DefaultChannel string
+
+	mu      sync.RWMutex
+	barkers map[string]Barker
+}
+
+// Register registers a Barker for a channel name, replacing any existing Barker for the channel.
+func (registry *BarkerRegistry) Register(channel string, barker Barker) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.barkers == nil {
+		registry.barkers = make(map[string]Barker)
+	}
+	registry.barkers[channel] = barker
+}
+
+// Get returns the Barker for a channel name.
+func (registry *BarkerRegistry) Get(channel string) (Barker, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	barker, found := registry.barkers[channel]
+	if !found {
+		return nil, ErrChannelNotRegistered
+	}
+	return barker, nil
+}
+
+// Channels returns the sorted names of all registered channels.
+func (registry *BarkerRegistry) Channels() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	channels := make([]string, 0, len(registry.barkers))
+	for channel := range registry.barkers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Deliveries returns the deliveries for a dog, falling back to the default channel when the dog
+// has none.
+func (registry *BarkerRegistry) Deliveries(dog *Dog) []Delivery {
+	if len(dog.Deliveries) == 0 && registry.DefaultChannel != "" {
+		return []Delivery{{Channel: registry.DefaultChannel}}
+	}
+	return dog.Deliveries
+}
+
+// deliverySecretSize is the number of random bytes in a delivery secret.
+const deliverySecretSize = 32
+
+// GenerateSecrets generates a secret for each delivery to a channel whose barker signs barks and
+// returns the generated secrets.
+func (registry *BarkerRegistry) GenerateSecrets(deliveries []Delivery) ([]DeliverySecret, error) {
+	var secrets []DeliverySecret
+	for i, delivery := range deliveries {
+		barker, err := registry.Get(delivery.Channel)
+		if err != nil {
+			return nil, err
+		}
+		if signer, ok := barker.(SigningBarker); !ok || !signer.SignsBarks() {
+			continue
+		}
+
+		secret := make([]byte, deliverySecretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		deliveries[i].Secret = hex.EncodeToString(secret)
+		secrets = append(secrets, DeliverySecret{
+			Channel: delivery.Channel,
+			Address: delivery.Address,
+			Secret:  deliveries[i].Secret,
+		})
+	}
+	return secrets, nil
+}
+
+// deliverySecret returns the secret of a dog's delivery to a channel and address.
+func (dog *Dog) deliverySecret(channel, address string) string {
+	for _, delivery := range dog.Deliveries {
+		if delivery.Channel == channel && delivery.Address == address {
+			return delivery.Secret
+		}
+	}
+	return ""
+}
@@ -0,0 +1,344 @@
+package dog_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/delivery"
+	"github.com/dgravesa/bark/pkg/dog"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// barkingTasks is a memoryTasks that also claims, reschedules and retries barks.
+type barkingTasks struct {
+	memoryTasks
+	retries []*dog.BarkRecord
+}
+
+func (tasks *barkingTasks) Claim(ctx context.Context, dogID, taskName string) (*dog.Dog, error) {
+	d, found := tasks.dogs[dogID]
+	switch {
+	case !found:
+		return nil, status.Errorf(codes.NotFound, "dog not found with ID: %s", dogID)
+	case d.NextTaskName != taskName:
+		return nil, dog.ErrTaskStale
+	case d.ClaimedTaskName == taskName:
+		return nil, dog.ErrTaskClaimed
+	}
+	d.ClaimedTaskName = taskName
+	return d, nil
+}
+
+func (tasks *barkingTasks) Get(ctx context.Context, dogID string) (*dog.Dog, error) {
+	d, found := tasks.dogs[dogID]
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "dog not found with ID: %s", dogID)
+	}
+	return d, nil
+}
+
+func (tasks *barkingTasks) Reschedule(ctx context.Context, d *dog.Dog) (*dog.Dog, error) {
+	return tasks.Register(ctx, d)
+}
+
+func (tasks *barkingTasks) ScheduleRetry(ctx context.Context, record *dog.BarkRecord, _ time.Time) error {
+	tasks.retries = append(tasks.retries, record)
+	return nil
+}
+
+// recordedBarks is an in-memory BarkStore that only records puts.
+type recordedBarks struct {
+	dog.BarkStore
+	records []*dog.BarkRecord
+}
+
+func (store *recordedBarks) Put(ctx context.Context, record *dog.BarkRecord) error {
+	store.records = append(store.records, record)
+	return nil
+}
+
+func TestBarkDog(t *testing.T) {
+	tests := []struct {
+		name      string
+		dogID     string
+		taskName  string
+		claimed   bool
+		wantCode  int
+		wantBarks int
+	}{
+		{name: "next task", dogID: "dog1", taskName: "task-dog1", wantCode: http.StatusOK, wantBarks: 1},
+		{name: "stale task", dogID: "dog1", taskName: "task-old", wantCode: http.StatusNoContent},
+		{name: "task in flight", dogID: "dog1", taskName: "task-dog1", claimed: true, wantCode: http.StatusConflict},
+		{name: "missing task name", dogID: "dog1", wantCode: http.StatusBadRequest},
+		{name: "unknown dog", dogID: "dog2", taskName: "task-dog2", wantCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tasks := &barkingTasks{}
+			tasks.Register(context.Background(), &dog.Dog{
+				ID:           "dog1",
+				IdeaID:       "idea1",
+				ScheduleType: "cron",
+				ScheduleRaw:  json.RawMessage(`"0 9 * * *"`),
+				Deliveries: []dog.Delivery{
+					{Channel: "recorder", Address: "somewhere"},
+					{Channel: "failing"},
+				},
+			})
+			if test.claimed {
+				tasks.dogs["dog1"].ClaimedTaskName = "task-dog1"
+			}
+			recorder := &delivery.Recorder{}
+			barkers := &dog.BarkerRegistry{}
+			barkers.Register("recorder", recorder)
+			barkers.Register("failing", &delivery.Recorder{Err: errors.New("unreachable")})
+			history := &recordedBarks{}
+			service := &dog.Service{
+				IdeaGetter:  ideaMap{"idea1": {ID: "idea1", Text: "drink water"}},
+				TasksClient: tasks,
+				Barkers:     barkers,
+				BarkStore:   history,
+				RetryPolicy: dog.RetryPolicy{MaxAttempts: 3},
+				Hub:         &dog.BarkHub{},
+			}
+
+			router := chi.NewRouter()
+			service.RegisterRoutes(router)
+			req := httptest.NewRequest(http.MethodPost, "/dogs/"+test.dogID+"/bark", nil)
+			if test.taskName != "" {
+				req.Header.Set(dog.TaskNameHeader, test.taskName)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantCode, w.Body)
+			}
+			barks := recorder.Barks()
+			if len(barks) != test.wantBarks {
+				t.Fatalf("recorded %d barks, want %d", len(barks), test.wantBarks)
+			}
+			if test.wantBarks == 0 {
+				if len(history.records) != 0 {
+					t.Errorf("recorded %d history records, want none", len(history.records))
+				}
+				return
+			}
+
+			if b := barks[0]; b.Idea.Text != "drink water" || b.Address != "somewhere" || b.Attempt != 1 {
+				t.Errorf("bark = %+v, want idea text, address and first attempt", b)
+			}
+			// each channel is recorded, and the failed delivery is retried
+			outcomes := map[string]string{}
+			for _, record := range history.records {
+				outcomes[record.Channel] = record.Outcome
+			}
+			if outcomes["recorder"] != dog.BarkDelivered || outcomes["failing"] != dog.BarkFailed {
+				t.Errorf("outcomes = %v", outcomes)
+			}
+			if len(tasks.retries) != 1 || tasks.retries[0].Channel != "failing" {
+				t.Errorf("retries = %v, want the failed delivery", tasks.retries)
+			}
+			if d := tasks.dogs["dog1"]; d.BarkCount != 1 {
+				t.Errorf("bark count = %d, want 1", d.BarkCount)
+			}
+		})
+	}
+}
+
+// endedDigests is a DigestStore of digests without upcoming deliveries.
+type endedDigests struct {
+	dog.DigestStore
+}
+
+func (endedDigests) Append(ctx context.Context, digestID string, entries ...dog.DigestEntry) (int, error) {
+	return 0, dog.ErrDigestEnded
+}
+
+func TestBarkDogEndedDigest(t *testing.T) {
+	tasks := &barkingTasks{}
+	tasks.Register(context.Background(), &dog.Dog{
+		ID:           "dog1",
+		IdeaID:       "idea1",
+		DigestID:     "digest1",
+		ScheduleType: "cron",
+		ScheduleRaw:  json.RawMessage(`"0 9 * * *"`),
+		Deliveries:   []dog.Delivery{{Channel: "recorder"}},
+	})
+	recorder := &delivery.Recorder{}
+	barkers := &dog.BarkerRegistry{}
+	barkers.Register("recorder", recorder)
+	service := &dog.Service{
+		IdeaGetter:  ideaMap{"idea1": {ID: "idea1", Text: "drink water"}},
+		TasksClient: tasks,
+		Barkers:     barkers,
+		BarkStore:   &recordedBarks{},
+		Digests:     endedDigests{},
+		Hub:         &dog.BarkHub{},
+	}
+
+	router := chi.NewRouter()
+	service.RegisterRoutes(router)
+	req := httptest.NewRequest(http.MethodPost, "/dogs/dog1/bark", nil)
+	req.Header.Set(dog.TaskNameHeader, "task-dog1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if barks := recorder.Barks(); len(barks) != 1 || barks[0].Digest != nil {
+		t.Errorf("recorded %d barks, want the bark delivered individually", len(barks))
+	}
+}
+
+func TestWebhookDeliverySecret(t *testing.T) {
+	var webhookSecret []byte
+	var signed int
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(delivery.WebhookSignatureHeader) == "" {
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		signed++
+		verifyErr = delivery.VerifyWebhookSignature(webhookSecret, r.Header, body, time.Minute)
+	}))
+	defer server.Close()
+
+	tasks := &barkingTasks{}
+	barkers := &dog.BarkerRegistry{}
+	barkers.Register("webhook", &delivery.WebhookBarker{Client: server.Client()})
+	barkers.Register("slack", &delivery.WebhookBarker{Unsigned: true, Client: server.Client()})
+	service := &dog.Service{
+		IdeaGetter:  ideaMap{"idea1": {ID: "idea1", Text: "drink water"}},
+		DogGetter:   tasks,
+		TasksClient: tasks,
+		Barkers:     barkers,
+		BarkStore:   &recordedBarks{},
+		Hub:         &dog.BarkHub{},
+	}
+
+	// the secret of the signed delivery is returned once, at creation
+	w := serve(service, http.MethodPost, "/dogs", `{"ideaId": "idea1", "scheduleType": "cron",
+		"schedule": "0 9 * * *", "deliveries": [
+			{"channel": "webhook", "address": "`+server.URL+`"},
+			{"channel": "slack", "address": "`+server.URL+`"}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var created dog.CreateDogResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Secrets) != 1 || created.Secrets[0].Channel != "webhook" || created.Secrets[0].Secret == "" {
+		t.Fatalf("secrets = %+v, want a secret for the webhook delivery only", created.Secrets)
+	}
+	webhookSecret = []byte(created.Secrets[0].Secret)
+	if stored := tasks.dogs[created.ID].Deliveries[0].Secret; stored != created.Secrets[0].Secret {
+		t.Errorf("stored secret = %q, want the returned secret", stored)
+	}
+
+	w = serve(service, http.MethodGet, "/dogs/"+created.ID, "")
+	if strings.Contains(w.Body.String(), created.Secrets[0].Secret) {
+		t.Errorf("GetDog returned the delivery secret: %s", w.Body)
+	}
+
+	// barks to the delivery are signed with its secret
+	router := chi.NewRouter()
+	service.RegisterRoutes(router)
+	req := httptest.NewRequest(http.MethodPost, "/dogs/"+created.ID+"/bark", nil)
+	req.Header.Set(dog.TaskNameHeader, tasks.dogs[created.ID].NextTaskName)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bark status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if signed != 1 || verifyErr != nil {
+		t.Errorf("signed requests = %d, VerifyWebhookSignature() = %v, want 1 verified request",
+			signed, verifyErr)
+	}
+}
+
+// storedDogs is an in-memory DoggoStore of claims and field updates, for barking through a
+// Whisperer.
+type storedDogs struct {
+	dog.DoggoStore
+	dogs    map[string]*dog.Dog
+	updates map[string]interface{}
+}
+
+func (store *storedDogs) ClaimTask(ctx context.Context, dogID, taskName string) (*dog.Dog, error) {
+	d, found := store.dogs[dogID]
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "dog not found with ID: %s", dogID)
+	}
+	if d.NextTaskName != taskName {
+		return nil, dog.ErrTaskStale
+	}
+	claimed := *d
+	return &claimed, nil
+}
+
+func (store *storedDogs) Update(ctx context.Context, dogID string, fields map[string]interface{}) error {
+	store.updates = fields
+	return nil
+}
+
+func TestBarkAtDogCompletes(t *testing.T) {
+	barkTime := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	schedule, _ := json.Marshal(barkTime)
+	store := &storedDogs{dogs: map[string]*dog.Dog{
+		"dog1": {
+			ID:           "dog1",
+			IdeaID:       "idea1",
+			ScheduleType: "at",
+			ScheduleRaw:  schedule,
+			NextTaskName: "task-at",
+			NextTaskTime: barkTime,
+			Deliveries:   []dog.Delivery{{Channel: "recorder"}},
+		},
+	}}
+	recorder := &delivery.Recorder{}
+	barkers := &dog.BarkerRegistry{}
+	barkers.Register("recorder", recorder)
+	service := &dog.Service{
+		IdeaGetter:  ideaMap{"idea1": {ID: "idea1", Text: "drink water"}},
+		TasksClient: dog.Whisperer{DogStore: store},
+		Barkers:     barkers,
+		BarkStore:   &recordedBarks{},
+		Hub:         &dog.BarkHub{},
+	}
+
+	router := chi.NewRouter()
+	service.RegisterRoutes(router)
+	req := httptest.NewRequest(http.MethodPost, "/dogs/dog1/bark", nil)
+	req.Header.Set(dog.TaskNameHeader, "task-at")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if barks := recorder.Barks(); len(barks) != 1 || !barks[0].ScheduledTime.Equal(barkTime) {
+		t.Errorf("recorded %d barks, want one at %s", len(barks), barkTime)
+	}
+	if store.updates["completed"] != true || store.updates["nextTaskName"] != "" || store.updates["barkCount"] != 1 {
+		t.Errorf("updates = %v, want a completed dog without a task", store.updates)
+	}
+	var responded dog.Dog
+	json.NewDecoder(w.Body).Decode(&responded)
+	if !responded.Completed || responded.NextTaskName != "" {
+		t.Errorf("responded dog = %+v, want completed without a task", responded)
+	}
+}
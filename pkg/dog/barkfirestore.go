@@ -0,0 +1,140 @@
+package dog
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BarkFirestore is a Google Cloud Firestore-based data backend for the bark history.
+//
+// Listing a dog's barks requires a composite index on the barks collection of dogId ascending
+// and actualTime descending.
+type BarkFirestore struct {
+	FirestoreClient *firestore.Client
+}
+
+// Get returns a bark record by ID.
+func (store *BarkFirestore) Get(ctx context.Context, ID string) (*BarkRecord, error) {
+	// get document from datastore
+	docID := "barks/" + ID
+	recordDoc, err := store.FirestoreClient.Doc(docID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert to record
+	var record BarkRecord
+	err = recordDoc.DataTo(&record)
+	return &record, err
+}
+
+// Put inserts a bark record.
+func (store *BarkFirestore) Put(ctx context.Context, record *BarkRecord) error {
+	docID := "barks/" + record.ID
+	_, err := store.FirestoreClient.Doc(docID).Create(ctx, record)
+	return err
+}
+
+// Update writes an existing bark record, replacing all of its fields.
+func (store *BarkFirestore) Update(ctx context.Context, record *BarkRecord) error {
+	docID := "barks/" + record.ID
+	_, err := store.FirestoreClient.Doc(docID).Set(ctx, record)
+	return err
+}
+
+// ListByDog returns a page of a dog's bark records, most recent first. The page token is the ID
+// of the last record of the previous page.
+func (store *BarkFirestore) ListByDog(ctx context.Context, dogID string, pageSize int,
+	pageToken string) ([]*BarkRecord, string, error) {
+
+	query := store.FirestoreClient.Collection("barks").
+		Where("dogId", "==", dogID).
+		OrderBy("actualTime", firestore.Desc).
+		Limit(pageSize)
+
+	// resume after last record of previous page
+	if pageToken != "" {
+		lastDoc, err := store.FirestoreClient.Doc("barks/" + pageToken).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, "", ErrInvalidPageToken
+		} else if err != nil {
+			return nil, "", err
+		}
+		if dogIDField, _ := lastDoc.DataAt("dogId"); dogIDField != dogID {
+			return nil, "", ErrInvalidPageToken
+		}
+		query = query.StartAfter(lastDoc)
+	}
+
+	records, err := readBarkRecords(query.Documents(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextPageToken string
+	if len(records) == pageSize {
+		nextPageToken = records[len(records)-1].ID
+	}
+	return records, nextPageToken, nil
+}
+
+// ListSince returns up to limit bark records of any dog recorded after the given record, oldest
+// first. Records are listed from the oldest if the ID is empty.
+func (store *BarkFirestore) ListSince(ctx context.Context, ID string,
+	limit int) ([]*BarkRecord, error) {
+
+	query := store.FirestoreClient.Collection("barks").
+		OrderBy("actualTime", firestore.Asc).
+		Limit(limit)
+
+	if ID != "" {
+		lastDoc, err := store.FirestoreClient.Doc("barks/" + ID).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrInvalidPageToken
+		} else if err != nil {
+			return nil, err
+		}
+		query = query.StartAfter(lastDoc)
+	}
+
+	return readBarkRecords(query.Documents(ctx))
+}
+
+// Latest returns the most recently recorded bark record of any dog, or nil if there are none.
+func (store *BarkFirestore) Latest(ctx context.Context) (*BarkRecord, error) {
+	query := store.FirestoreClient.Collection("barks").
+		OrderBy("actualTime", firestore.Desc).
+		Limit(1)
+
+	records, err := readBarkRecords(query.Documents(ctx))
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// readBarkRecords reads all bark records from a document iterator.
+func readBarkRecords(iter *firestore.DocumentIterator) ([]*BarkRecord, error) {
+	defer iter.Stop()
+
+	records := []*BarkRecord{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var record BarkRecord
+		if err = doc.DataTo(&record); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, nil
+}
@@ -0,0 +1,49 @@
+package dog
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Bark outcomes recorded in the bark history.
+const (
+	BarkDelivered = "delivered"
+	BarkFailed    = "failed"
+)
+
+// A BarkRecord is the history entry for a single delivery of a Dog's Idea to a channel.
+type BarkRecord struct {
+	ID            string    `json:"id" firestore:"id"`
+	DogID         string    `json:"dogId" firestore:"dogId"`
+	IdeaID        string    `json:"ideaId" firestore:"ideaId"`
+	TaskName      string    `json:"taskName" firestore:"taskName"`
+	ScheduledTime time.Time `json:"scheduledTime" firestore:"scheduledTime"`
+	ActualTime    time.Time `json:"actualTime" firestore:"actualTime"`
+	Channel       string    `json:"channel" firestore:"channel"`
+	Address       string    `json:"address,omitempty" firestore:"address,omitempty"`
+	Attempt       int       `json:"attempt" firestore:"attempt"`
+	Outcome       string    `json:"outcome" firestore:"outcome"`
+	ErrorText     string    `json:"errorText,omitempty" firestore:"errorText,omitempty"`
+}
+
+// ErrInvalidPageToken is returned when listing with a page token that does not refer to a
+// previous page.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// BarkStore is a data store for the bark history.
+type BarkStore interface {
+	Get(ctx context.Context, ID string) (*BarkRecord, error)
+	Put(ctx context.Context, record *BarkRecord) error
+	Update(ctx context.Context, record *BarkRecord) error
+	// ListByDog returns a page of a dog's bark records, most recent first, and the token for the
+	// next page. The next page token is empty when there are no more records.
+	ListByDog(ctx context.Context, dogID string, pageSize int,
+		pageToken string) ([]*BarkRecord, string, error)
+	// ListSince returns up to limit bark records of any dog recorded after the given record,
+	// oldest first, or from the oldest record if the ID is empty. ErrInvalidPageToken is returned
+	// if the given record does not exist.
+	ListSince(ctx context.Context, ID string, limit int) ([]*BarkRecord, error)
+	// Latest returns the most recently recorded bark record, or nil if there are none.
+	Latest(ctx context.Context) (*BarkRecord, error)
+}
@@ -0,0 +1,75 @@
+package dog
+
+import (
+	"errors"
+	"time"
+)
+
+// Bounds limit a dog's barks to a period and a number of barks. Unset bounds do not limit.
+type Bounds struct {
+	StartAt  *time.Time `json:"startAt,omitempty" firestore:"startAt,omitempty"`
+	EndAt    *time.Time `json:"endAt,omitempty" firestore:"endAt,omitempty"`
+	MaxBarks int        `json:"maxBarks,omitempty" firestore:"maxBarks,omitempty"`
+}
+
+// Validate returns an error if the bounds are malformed.
+func (b *Bounds) Validate() error {
+	if b == nil {
+		return nil
+	}
+	if b.StartAt != nil && b.EndAt != nil && !b.EndAt.After(*b.StartAt) {
+		return errors.New("endAt must be after startAt")
+	}
+	if b.MaxBarks < 0 {
+		return errors.New("maxBarks must not be negative")
+	}
+	return nil
+}
+
+// Wrap returns a Schedule that limits the times of s to the bounds, given the number of barks
+// already made.
+func (b *Bounds) Wrap(s Schedule, barkCount int) Schedule {
+	if b == nil {
+		return s
+	}
+	bounded := &BoundedSchedule{
+		Schedule:  s,
+		Remaining: -1,
+	}
+	if b.StartAt != nil {
+		bounded.StartAt = *b.StartAt
+	}
+	if b.EndAt != nil {
+		bounded.EndAt = *b.EndAt
+	}
+	if b.MaxBarks > 0 {
+		bounded.Remaining = b.MaxBarks - barkCount
+	}
+	return bounded
+}
+
+// BoundedSchedule is a Schedule whose times are limited to a period and a number of times.
+// Deferrals must be applied within the bounded schedule, so that deferred times are also limited.
+type BoundedSchedule struct {
+	Schedule
+	// StartAt and EndAt bound the times of the schedule, inclusively, unless zero.
+	StartAt time.Time
+	EndAt   time.Time
+	// Remaining is the number of times left, or negative if unlimited.
+	Remaining int
+}
+
+// Next implements the Schedule interface.
+func (s *BoundedSchedule) Next(t time.Time) time.Time {
+	if s.Remaining == 0 {
+		return time.Time{}
+	}
+	if t.Before(s.StartAt) {
+		t = s.StartAt.Add(-time.Nanosecond)
+	}
+	next := s.Schedule.Next(t)
+	if !s.EndAt.IsZero() && next.After(s.EndAt) {
+		return time.Time{}
+	}
+	return next
+}
@@ -0,0 +1,105 @@
+package dog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBoundedScheduleNext(t *testing.T) {
+	hourly := IntervalSchedule{
+		Every:  time.Hour,
+		Anchor: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	startAt := time.Date(2024, 1, 10, 6, 0, 0, 0, time.UTC)
+	endAt := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 1, 5, 0, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		bounds    *Bounds
+		barkCount int
+		after     time.Time
+		want      time.Time
+	}{
+		{
+			name:  "unbounded",
+			after: now,
+			want:  time.Date(2024, 1, 5, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "before start includes start",
+			bounds: &Bounds{StartAt: &startAt},
+			after:  now,
+			want:   startAt,
+		},
+		{
+			name:   "end is inclusive",
+			bounds: &Bounds{EndAt: &endAt},
+			after:  endAt.Add(-time.Minute),
+			want:   endAt,
+		},
+		{
+			name:   "after end",
+			bounds: &Bounds{EndAt: &endAt},
+			after:  endAt,
+		},
+		{
+			name:      "barks remaining",
+			bounds:    &Bounds{MaxBarks: 3},
+			barkCount: 2,
+			after:     now,
+			want:      time.Date(2024, 1, 5, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "no barks remaining",
+			bounds:    &Bounds{MaxBarks: 3},
+			barkCount: 3,
+			after:     now,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.bounds.Wrap(hourly, test.barkCount).Next(test.after)
+			if !got.Equal(test.want) {
+				t.Errorf("Next(%s) = %s, want %s", test.after, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBoundedScheduleDeferredPastEnd(t *testing.T) {
+	// the last daily time before the end is deferred by quiet hours past the end
+	daily := IntervalSchedule{
+		Every:  24 * time.Hour,
+		Anchor: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	endAt := time.Date(2024, 1, 10, 9, 30, 0, 0, time.UTC)
+	quiet := QuietHours{{Start: "08:00", End: "10:00"}}
+
+	schedule := (&Bounds{EndAt: &endAt}).Wrap(quiet.Wrap(daily), 0)
+	if got := schedule.Next(time.Date(2024, 1, 9, 12, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next() = %s, want zero time for a time deferred past endAt", got)
+	}
+}
+
+func TestBoundsValidate(t *testing.T) {
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+
+	tests := []struct {
+		bounds  *Bounds
+		wantErr bool
+	}{
+		{bounds: nil},
+		{bounds: &Bounds{StartAt: &early, EndAt: &late, MaxBarks: 10}},
+		{bounds: &Bounds{StartAt: &late, EndAt: &early}, wantErr: true},
+		{bounds: &Bounds{StartAt: &early, EndAt: &early}, wantErr: true},
+		{bounds: &Bounds{MaxBarks: -1}, wantErr: true},
+	}
+	for i, test := range tests {
+		if err := test.bounds.Validate(); (err != nil) != test.wantErr {
+			t.Errorf("%d: Validate() = %v, want error %v", i, err, test.wantErr)
+		}
+	}
+}
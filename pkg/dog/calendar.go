@@ -0,0 +1,275 @@
+package dog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxExcludedDays bounds how many consecutive excluded days a time may be rolled forward through.
+const maxExcludedDays = 366
+
+// A Calendar is a set of days on which dogs referencing the calendar do not bark. Barks that fall
+// on an excluded day roll forward to the same time on the next allowed day.
+//
+// ExcludeDays excludes days of the week, as three-letter names such as "sat", or "weekend" for
+// Saturday and Sunday. Holidays exclude individual dates, and are typically imported from an
+// iCalendar file. Days are evaluated in TimeZone, an IANA time zone name, or UTC if empty.
+//
+// Recurring holidays are expanded when imported, through HolidaysUntil. Holidays must be imported
+// again before then for recurring holidays to continue to apply.
+type Calendar struct {
+	ID           string    `json:"id" firestore:"id"`
+	UserID       string    `json:"userId,omitempty" firestore:"userId,omitempty"`
+	CreationTime time.Time `json:"creationTime" firestore:"creationTime"`
+	Name         string    `json:"name,omitempty" firestore:"name,omitempty"`
+	ExcludeDays  []string  `json:"excludeDays,omitempty" firestore:"excludeDays,omitempty"`
+	TimeZone     string    `json:"timeZone,omitempty" firestore:"timeZone,omitempty"`
+	Holidays     []Holiday `json:"holidays" firestore:"holidays"`
+
+	HolidaysUntil *time.Time `json:"holidaysUntil,omitempty" firestore:"holidaysUntil,omitempty"`
+}
+
+// A Holiday is an excluded date of a calendar, in "2006-01-02" format.
+type Holiday struct {
+	Date string `json:"date" firestore:"date"`
+	Name string `json:"name,omitempty" firestore:"name,omitempty"`
+}
+
+// CalendarGetter is an interface for getting calendars.
+type CalendarGetter interface {
+	Get(ctx context.Context, ID string) (*Calendar, error)
+}
+
+// CalendarStore is a data store for calendars.
+type CalendarStore interface {
+	Get(ctx context.Context, ID string) (*Calendar, error)
+	Set(ctx context.Context, calendar *Calendar) error
+	Delete(ctx context.Context, ID string) error
+}
+
+// Validate returns an error if the calendar's excluded days or time zone are malformed.
+func (calendar *Calendar) Validate() error {
+	days, err := calendar.excludedWeekdays()
+	if err != nil {
+		return err
+	}
+	if len(days) == len(weekdays) {
+		return errors.New("calendar must not exclude every day of the week")
+	}
+	if _, err = time.LoadLocation(calendar.TimeZone); err != nil {
+		return fmt.Errorf("invalid calendar time zone: %s", calendar.TimeZone)
+	}
+	return nil
+}
+
+// Wrap returns a Schedule that rolls the times of s forward past the calendar's excluded days.
+// Times are rolled forward together with any other deferrals of s, such as quiet hours.
+func (calendar *Calendar) Wrap(s Schedule) Schedule {
+	days, err := calendar.excludedWeekdays()
+	if err != nil {
+		return s
+	}
+	loc, err := time.LoadLocation(calendar.TimeZone)
+	if err != nil {
+		return s
+	}
+
+	holidays := make(map[string]bool, len(calendar.Holidays))
+	for _, holiday := range calendar.Holidays {
+		holidays[holiday.Date] = true
+	}
+
+	return withDeferral(s, &calendarDays{
+		loc:      loc,
+		days:     days,
+		holidays: holidays,
+	})
+}
+
+// excludedWeekdays parses the calendar's excluded days of the week.
+func (calendar *Calendar) excludedWeekdays() (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, day := range calendar.ExcludeDays {
+		day = strings.ToLower(day)
+		if day == "weekend" {
+			days[time.Saturday] = true
+			days[time.Sunday] = true
+			continue
+		}
+		weekday, found := weekdays[day]
+		if !found {
+			return nil, fmt.Errorf("invalid calendar day: %s", day)
+		}
+		days[weekday] = true
+	}
+	return days, nil
+}
+
+// calendarDays is a Deferral that rolls times forward past the excluded days of a calendar.
+type calendarDays struct {
+	loc      *time.Location
+	days     map[time.Weekday]bool
+	holidays map[string]bool
+}
+
+// Defer implements the Deferral interface.
+func (c *calendarDays) Defer(t time.Time) time.Time {
+	local := t.In(c.loc)
+	for i := 0; i <= maxExcludedDays; i++ {
+		if !c.days[local.Weekday()] && !c.holidays[local.Format("2006-01-02")] {
+			return local.In(t.Location())
+		}
+		local = local.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// CreateCalendarRequest is the request type for creating a new Calendar.
+type CreateCalendarRequest struct {
+	UserID      string   `json:"userId,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	ExcludeDays []string `json:"excludeDays,omitempty"`
+	TimeZone    string   `json:"timeZone,omitempty"`
+}
+
+var maxCreateCalendarRequestSizeBytes int64 = 20000
+
+var maxHolidaysRequestSizeBytes int64 = 1 << 20
+
+// PostCalendar is a handler for creating a new Calendar. The calendar has no holidays until they
+// are imported.
+func (service *Service) PostCalendar(w http.ResponseWriter, r *http.Request) {
+	var requestBody CreateCalendarRequest
+
+	// read request body into struct
+	r.Body = http.MaxBytesReader(w, r.Body, maxCreateCalendarRequestSizeBytes)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		service.Logf(r, `result=DecodeError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	calendar := &Calendar{
+		ID:           uuid.NewString(),
+		UserID:       requestBody.UserID,
+		CreationTime: time.Now(),
+		Name:         requestBody.Name,
+		ExcludeDays:  requestBody.ExcludeDays,
+		TimeZone:     requestBody.TimeZone,
+		Holidays:     []Holiday{},
+	}
+	if err = calendar.Validate(); err != nil {
+		service.Logf(r, `result=InvalidCalendarError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = service.Calendars.Set(r.Context(), calendar)
+	if err != nil {
+		service.Logf(r, `action=PutCalendar calendarID=%s result=InternalError errorText="%s"`,
+			calendar.ID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	service.Logf(r, `action=PutCalendar calendarID=%s result=OK`, calendar.ID)
+
+	bark.RespondSuccess(w, http.StatusCreated, calendar)
+}
+
+// GetCalendar is a handler for getting a calendar.
+func (service *Service) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	calendarID := chi.URLParam(r, "calendarID")
+
+	calendar, ok := service.getCalendar(w, r, calendarID)
+	if !ok {
+		return
+	}
+
+	bark.RespondSuccess(w, http.StatusOK, calendar)
+}
+
+// DeleteCalendar is a handler for deleting a calendar. Dogs referencing a deleted calendar bark
+// on every day.
+func (service *Service) DeleteCalendar(w http.ResponseWriter, r *http.Request) {
+	calendarID := chi.URLParam(r, "calendarID")
+
+	err := service.Calendars.Delete(r.Context(), calendarID)
+	if err != nil {
+		service.Logf(r, `action=DeleteCalendar calendarID=%s result=InternalError errorText="%s"`,
+			calendarID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	service.Logf(r, `action=DeleteCalendar calendarID=%s result=OK`, calendarID)
+	bark.RespondSuccess(w, http.StatusNoContent, nil)
+}
+
+// PutCalendarHolidays is a handler for importing a calendar's holidays from an iCalendar (.ics)
+// file in the request body, replacing its existing holidays. Holidays apply to each dog
+// referencing the calendar from its next scheduled bark. Recurring holidays are expanded for
+// maxHolidayYears, as reported by the calendar's HolidaysUntil.
+func (service *Service) PutCalendarHolidays(w http.ResponseWriter, r *http.Request) {
+	calendarID := chi.URLParam(r, "calendarID")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHolidaysRequestSizeBytes)
+	until := time.Now().AddDate(maxHolidayYears, 0, 0).Truncate(time.Second)
+	holidays, err := ParseICS(r.Body, until)
+	if err != nil {
+		service.Logf(r, `result=ParseICSError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	calendar, ok := service.getCalendar(w, r, calendarID)
+	if !ok {
+		return
+	}
+	calendar.Holidays = holidays
+	calendar.HolidaysUntil = &until
+
+	err = service.Calendars.Set(r.Context(), calendar)
+	if err != nil {
+		service.Logf(r, `action=PutCalendar calendarID=%s result=InternalError errorText="%s"`,
+			calendarID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	service.Logf(r, `action=PutCalendar calendarID=%s holidays=%d holidaysUntil=%s result=OK`,
+		calendarID, len(holidays), until.Format(time.RFC3339))
+
+	bark.RespondSuccess(w, http.StatusOK, calendar)
+}
+
+// getCalendar gets a calendar, responding with an error if it cannot.
+func (service *Service) getCalendar(w http.ResponseWriter, r *http.Request,
+	calendarID string) (*Calendar, bool) {
+
+	calendar, err := service.Calendars.Get(r.Context(), calendarID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetCalendar calendarID=%s result=OK`, calendarID)
+		return calendar, true
+	case codes.NotFound:
+		service.Logf(r, `action=GetCalendar calendarID=%s result=NotFoundError`, calendarID)
+		bark.RespondError(w, http.StatusNotFound,
+			fmt.Sprint("calendar not found with ID: ", calendarID))
+	default:
+		service.Logf(r, `action=GetCalendar calendarID=%s result=InternalError errorText="%s"`,
+			calendarID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package dog
+
+import (
+	"testing"
+	"time"
+)
+
+// fixedTimes is a Schedule that fires at each of its times, which are in order.
+type fixedTimes []time.Time
+
+func (times fixedTimes) Next(t time.Time) time.Time {
+	for _, next := range times {
+		if next.After(t) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+func TestCalendarWrap(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar := &Calendar{
+		ExcludeDays: []string{"weekend"},
+		TimeZone:    "Asia/Tokyo",
+		Holidays:    []Holiday{{Date: "2024-05-03", Name: "Constitution Day"}},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{
+			name: "weekday",
+			at:   time.Date(2024, 5, 2, 9, 0, 0, 0, tokyo),
+			want: time.Date(2024, 5, 2, 9, 0, 0, 0, tokyo),
+		},
+		{
+			name: "holiday then weekend",
+			at:   time.Date(2024, 5, 3, 9, 0, 0, 0, tokyo),
+			want: time.Date(2024, 5, 6, 9, 0, 0, 0, tokyo),
+		},
+		{
+			name: "weekday in UTC is weekend in calendar time zone",
+			at:   time.Date(2024, 5, 10, 23, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 5, 12, 23, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule := calendar.Wrap(fixedTimes{test.at})
+			got := schedule.Next(test.at.Add(-time.Hour))
+			if !got.Equal(test.want) {
+				t.Errorf("Next() = %s, want %s", got, test.want)
+			}
+			if got.Location() != test.at.Location() {
+				t.Errorf("Next() location = %s, want %s", got.Location(), test.at.Location())
+			}
+		})
+	}
+}
+
+func TestCalendarWrapWithQuietHours(t *testing.T) {
+	// a Saturday time rolls forward into Monday's quiet window, and is deferred again
+	calendar := &Calendar{ExcludeDays: []string{"sat", "sun"}}
+	quiet := QuietHours{{Start: "08:00", End: "09:00", Days: []string{"mon"}}}
+	saturday := fixedTimes{time.Date(2024, 5, 11, 8, 30, 0, 0, time.UTC)}
+	want := time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC)
+
+	for name, schedule := range map[string]Schedule{
+		"calendar outside": calendar.Wrap(quiet.Wrap(saturday)),
+		"quiet outside":    quiet.Wrap(calendar.Wrap(saturday)),
+	} {
+		if got := schedule.Next(time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)); !got.Equal(want) {
+			t.Errorf("%s: Next() = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestCalendarValidate(t *testing.T) {
+	valid := []Calendar{
+		{},
+		{ExcludeDays: []string{"weekend", "Fri"}, TimeZone: "Europe/Berlin"},
+	}
+	for _, calendar := range valid {
+		if err := calendar.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v, want nil", calendar, err)
+		}
+	}
+
+	invalid := []Calendar{
+		{ExcludeDays: []string{"caturday"}},
+		{ExcludeDays: []string{"weekend", "mon", "tue", "wed", "thu", "fri"}},
+		{TimeZone: "Nowhere/Special"},
+	}
+	for _, calendar := range invalid {
+		if err := calendar.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", calendar)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package dog
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+)
+
+// CalendarFirestore is a Google Cloud Firestore-based data backend for calendars.
+type CalendarFirestore struct {
+	FirestoreClient *firestore.Client
+}
+
+// Get returns a calendar by ID.
+func (store *CalendarFirestore) Get(ctx context.Context, ID string) (*Calendar, error) {
+	// get document from datastore
+	docID := "calendars/" + ID
+	calendarDoc, err := store.FirestoreClient.Doc(docID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert to calendar
+	var calendar Calendar
+	err = calendarDoc.DataTo(&calendar)
+	return &calendar, err
+}
+
+// Set writes a calendar, replacing any existing calendar with the same key.
+func (store *CalendarFirestore) Set(ctx context.Context, calendar *Calendar) error {
+	docID := "calendars/" + calendar.ID
+	_, err := store.FirestoreClient.Doc(docID).Set(ctx, calendar)
+	return err
+}
+
+// Delete deletes a calendar.
+func (store *CalendarFirestore) Delete(ctx context.Context, ID string) error {
+	docID := "calendars/" + ID
+	_, err := store.FirestoreClient.Doc(docID).Delete(ctx)
+	return err
+}
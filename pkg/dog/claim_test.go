@@ -0,0 +1,31 @@
+package dog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckClaim(t *testing.T) {
+	claimed := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	next := "projects/p/locations/l/queues/q/tasks/task1"
+
+	tests := []struct {
+		name            string
+		taskName        string
+		claimedTaskName string
+		now             time.Time
+		want            error
+	}{
+		{name: "unclaimed", taskName: "task1", now: claimed, want: nil},
+		{name: "other task", taskName: "task0", now: claimed, want: ErrTaskStale},
+		{name: "duplicate in flight", taskName: "task1", claimedTaskName: next, now: claimed.Add(time.Minute), want: ErrTaskClaimed},
+		{name: "crashed after claim", taskName: "task1", claimedTaskName: next, now: claimed.Add(claimLease), want: nil},
+		{name: "claim of earlier task", taskName: "task1", claimedTaskName: "task0", now: claimed, want: nil},
+	}
+
+	for _, test := range tests {
+		if err := checkClaim(test.taskName, next, test.claimedTaskName, claimed, test.now); err != test.want {
+			t.Errorf("%s: checkClaim() = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
@@ -2,29 +2,107 @@ package dog
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/robfig/cron"
 )
 
 // CronSchedule represents a Cron-based schedule.
+//
+// A CronSchedule with a time zone evaluates its spec on the wall clock of that zone. Times
+// skipped by a daylight saving transition fire at the corresponding time after the transition,
+// and times repeated by a transition fire only once. A CronSchedule without a time zone evaluates
+// its spec in the location of the time passed to Next.
 type CronSchedule struct {
-	spec string
+	spec     string
+	timeZone string
+	loc      *time.Location
 	cron.Schedule
 }
 
+func init() {
+	RegisterScheduleType("cron", func(b json.RawMessage) (Schedule, error) {
+		var schedule CronSchedule
+		err := json.Unmarshal(b, &schedule)
+		return schedule, err
+	})
+}
+
+// cronScheduleJSON is the JSON object form of a CronSchedule.
+type cronScheduleJSON struct {
+	Spec     string `json:"spec"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
 // Next implements the Schedule interface.
 func (s CronSchedule) Next(t time.Time) time.Time {
-	schedule, _ := cron.Parse(s.spec)
-	return schedule.Next(t)
+	if s.loc == nil {
+		return s.Schedule.Next(t)
+	}
+
+	// evaluate the spec on wall clock time, represented in UTC so there are no transitions
+	wall := wallClock(t.In(s.loc))
+	for {
+		wall = s.Schedule.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+		next := time.Date(wall.Year(), wall.Month(), wall.Day(),
+			wall.Hour(), wall.Minute(), wall.Second(), 0, s.loc)
+		// a wall clock time skipped by a transition is moved forward by the length of the gap
+		if got := wallClock(next); !got.Equal(wall) {
+			next = next.Add(wall.Sub(got))
+		}
+		if next.After(t) {
+			return next
+		}
+	}
+}
+
+// wallClock returns the wall clock time of t as a time in UTC.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
 }
 
-// UnmarshalJSON parses a JSON string into a CronSchedule.
+// UnmarshalJSON parses a JSON string, or a JSON object with "spec" and "timeZone" fields, into a
+// CronSchedule. A string may begin with a "CRON_TZ=" or "TZ=" prefix that sets the time zone.
 func (s *CronSchedule) UnmarshalJSON(b []byte) error {
-	err := json.Unmarshal(b, &s.spec)
+	var raw cronScheduleJSON
+	err := json.Unmarshal(b, &raw.Spec)
 	if err != nil {
-		return err
+		if err = json.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+	}
+
+	// split time zone prefix from spec
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if strings.HasPrefix(raw.Spec, prefix) {
+			if raw.TimeZone != "" {
+				return fmt.Errorf("time zone set twice for spec: %s", raw.Spec)
+			}
+			fields := strings.SplitN(strings.TrimPrefix(raw.Spec, prefix), " ", 2)
+			raw.TimeZone = fields[0]
+			raw.Spec = ""
+			if len(fields) > 1 {
+				raw.Spec = strings.TrimSpace(fields[1])
+			}
+		}
 	}
+
+	s.spec = raw.Spec
+	s.timeZone = raw.TimeZone
+	s.loc = nil
+	if s.timeZone != "" {
+		s.loc, err = time.LoadLocation(s.timeZone)
+		if err != nil {
+			return fmt.Errorf("invalid time zone: %s", s.timeZone)
+		}
+	}
+
 	// validate spec
 	s.Schedule, err = cron.ParseStandard(s.spec)
 	if err != nil {
@@ -33,7 +111,14 @@ func (s *CronSchedule) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-// MarshalJSON writes the Cron spec as a JSON string.
-func (s *CronSchedule) MarshalJSON() ([]byte, error) {
-	return json.Marshal(s.spec)
+// MarshalJSON writes the Cron spec as a JSON string, or as a JSON object if the schedule has a
+// time zone.
+func (s CronSchedule) MarshalJSON() ([]byte, error) {
+	if s.timeZone == "" {
+		return json.Marshal(s.spec)
+	}
+	return json.Marshal(&cronScheduleJSON{
+		Spec:     s.spec,
+		TimeZone: s.timeZone,
+	})
 }
@@ -0,0 +1,106 @@
+package dog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name  string
+		raw   string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "without time zone",
+			raw:   `"0 9 * * *"`,
+			after: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "time zone prefix",
+			raw:   `"CRON_TZ=America/New_York 0 9 * * *"`,
+			after: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 1, 1, 9, 0, 0, 0, newYork),
+		},
+		{
+			name:  "time zone object",
+			raw:   `{"spec": "0 9 * * *", "timeZone": "America/New_York"}`,
+			after: time.Date(2024, 7, 1, 14, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 7, 2, 9, 0, 0, 0, newYork),
+		},
+		{
+			name:  "skipped by spring forward",
+			raw:   `"TZ=America/New_York 30 2 * * *"`,
+			after: time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+			want:  time.Date(2024, 3, 10, 3, 30, 0, 0, newYork),
+		},
+		{
+			name:  "after spring forward",
+			raw:   `"TZ=America/New_York 30 2 * * *"`,
+			after: time.Date(2024, 3, 10, 3, 30, 0, 0, newYork),
+			want:  time.Date(2024, 3, 11, 2, 30, 0, 0, newYork),
+		},
+		{
+			name:  "repeated by fall back",
+			raw:   `"TZ=America/New_York 30 1 * * *"`,
+			after: time.Date(2024, 11, 3, 0, 0, 0, 0, newYork),
+			want:  time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 1:30 EDT
+		},
+		{
+			name:  "once during fall back",
+			raw:   `"TZ=America/New_York 30 1 * * *"`,
+			after: time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC),
+			want:  time.Date(2024, 11, 4, 1, 30, 0, 0, newYork),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseSchedule("cron", json.RawMessage(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(test.after); !got.Equal(test.want) {
+				t.Errorf("Next(%s) = %s, want %s", test.after, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCronScheduleJSON(t *testing.T) {
+	roundTrips := map[string]string{
+		`"0 9 * * *"`:                           `"0 9 * * *"`,
+		`"CRON_TZ=Asia/Tokyo 0 9 * * 1-5"`:      `{"spec":"0 9 * * 1-5","timeZone":"Asia/Tokyo"}`,
+		`{"spec":"0 9 * * *","timeZone":"UTC"}`: `{"spec":"0 9 * * *","timeZone":"UTC"}`,
+	}
+	for raw, want := range roundTrips {
+		var s CronSchedule
+		if err := json.Unmarshal([]byte(raw), &s); err != nil {
+			t.Errorf("Unmarshal(%s) = %v", raw, err)
+			continue
+		}
+		if got, _ := json.Marshal(s); string(got) != want {
+			t.Errorf("Marshal(Unmarshal(%s)) = %s, want %s", raw, got, want)
+		}
+	}
+
+	for _, raw := range []string{
+		`"every morning"`,
+		`"TZ=Mars/Olympus_Mons 0 9 * * *"`,
+		`{"spec":"TZ=UTC 0 9 * * *","timeZone":"UTC"}`,
+		`42`,
+	} {
+		var s CronSchedule
+		if err := json.Unmarshal([]byte(raw), &s); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", raw)
+		}
+	}
+}
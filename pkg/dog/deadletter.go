@@ -0,0 +1,119 @@
+package dog
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeadLetterStore is a data store for barks that failed all of their delivery attempts.
+// Dead letters are keyed by the ID of their bark record.
+type DeadLetterStore interface {
+	Get(ctx context.Context, ID string) (*BarkRecord, error)
+	Put(ctx context.Context, record *BarkRecord) error
+	Delete(ctx context.Context, ID string) error
+	// List returns a page of dead letters, most recent first, and the token for the next page.
+	// The next page token is empty when there are no more dead letters.
+	List(ctx context.Context, pageSize int, pageToken string) ([]*BarkRecord, string, error)
+}
+
+// requireAdmin is a middleware that refuses requests without the service's admin token as their
+// bearer token.
+func (service *Service) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := []byte(r.Header.Get("Authorization"))
+		if service.AdminToken == "" ||
+			subtle.ConstantTimeCompare(authorization, []byte("Bearer "+service.AdminToken)) != 1 {
+			service.Logf(r, `result=UnauthorizedError`)
+			bark.RespondError(w, http.StatusUnauthorized, "admin token required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ListDeadLetters is a handler for listing dead letters, most recent first.
+// Results are paginated with the pageSize and pageToken query parameters.
+func (service *Service) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	pageSize, pageToken, err := pageParams(r)
+	if err != nil {
+		service.Logf(r, `result=InvalidPageSizeError`)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, nextPageToken, err := service.DeadLetters.List(r.Context(), pageSize, pageToken)
+	switch err {
+	case nil:
+		service.Logf(r, `action=ListDeadLetters count=%d result=OK`, len(records))
+		bark.RespondSuccess(w, http.StatusOK, &ListBarksResponse{
+			Barks:         records,
+			NextPageToken: nextPageToken,
+		})
+	case ErrInvalidPageToken:
+		service.Logf(r, `action=ListDeadLetters result=InvalidPageTokenError`)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+	default:
+		service.Logf(r, `action=ListDeadLetters result=InternalError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+	}
+}
+
+// ReplayDeadLetter is a handler for making another delivery attempt of a dead letter.
+// The dead letter is removed if delivery succeeds.
+func (service *Service) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	barkID := chi.URLParam(r, "barkID")
+
+	record, err := service.DeadLetters.Get(r.Context(), barkID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetDeadLetter barkID=%s result=OK`, barkID)
+	case codes.NotFound:
+		service.Logf(r, `action=GetDeadLetter barkID=%s result=NotFoundError`, barkID)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("dead letter not found with ID: ", barkID))
+		return
+	default:
+		service.Logf(r, `action=GetDeadLetter barkID=%s result=InternalError errorText="%s"`,
+			barkID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	dog, idea, err := service.getBarkTarget(r, record)
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.NotFound:
+		bark.RespondError(w, http.StatusConflict, "dog or idea of bark no longer exists")
+		return
+	default:
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	record = service.redeliver(r, dog, idea, record, record.Attempt+1)
+	if record.Outcome == BarkFailed {
+		if err = service.DeadLetters.Put(r.Context(), record); err != nil {
+			service.Logf(r, `action=PutDeadLetter barkID=%s result=InternalError errorText="%s"`,
+				barkID, err)
+		}
+		bark.RespondError(w, http.StatusBadGateway, fmt.Sprint("replay failed: ", record.ErrorText))
+		return
+	}
+
+	err = service.DeadLetters.Delete(r.Context(), barkID)
+	if err != nil {
+		service.Logf(r, `action=DeleteDeadLetter barkID=%s result=InternalError errorText="%s"`,
+			barkID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	service.Logf(r, `action=DeleteDeadLetter barkID=%s result=OK`, barkID)
+
+	bark.RespondSuccess(w, http.StatusOK, record)
+}
@@ -0,0 +1,56 @@
+package dog_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgravesa/bark/pkg/dog"
+	"github.com/go-chi/chi/v5"
+)
+
+// emptyDeadLetters is a DeadLetterStore without any dead letters.
+type emptyDeadLetters struct {
+	dog.DeadLetterStore
+}
+
+func (emptyDeadLetters) List(ctx context.Context, pageSize int, pageToken string) ([]*dog.BarkRecord, string, error) {
+	return nil, "", nil
+}
+
+func TestDeadLettersRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		wantCode      int
+	}{
+		{name: "no token configured", authorization: "Bearer ", wantCode: http.StatusUnauthorized},
+		{name: "missing token", adminToken: "s3cret", wantCode: http.StatusUnauthorized},
+		{name: "wrong token", adminToken: "s3cret", authorization: "Bearer guess", wantCode: http.StatusUnauthorized},
+		{name: "token without scheme", adminToken: "s3cret", authorization: "s3cret", wantCode: http.StatusUnauthorized},
+		{name: "admin token", adminToken: "s3cret", authorization: "Bearer s3cret", wantCode: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &dog.Service{
+				DeadLetters: emptyDeadLetters{},
+				AdminToken:  test.adminToken,
+			}
+			router := chi.NewRouter()
+			service.RegisterRoutes(router)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/dead-letters", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != test.wantCode {
+				t.Errorf("status = %d, want %d: %s", w.Code, test.wantCode, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package dog
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeadLetterFirestore is a Google Cloud Firestore-based data backend for dead letters.
+type DeadLetterFirestore struct {
+	FirestoreClient *firestore.Client
+}
+
+// Get returns a dead letter by bark ID.
+func (store *DeadLetterFirestore) Get(ctx context.Context, ID string) (*BarkRecord, error) {
+	// get document from datastore
+	docID := "deadLetters/" + ID
+	recordDoc, err := store.FirestoreClient.Doc(docID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert to record
+	var record BarkRecord
+	err = recordDoc.DataTo(&record)
+	return &record, err
+}
+
+// Put inserts a dead letter. If there is an existing dead letter for the same bark, it will be
+// overwritten.
+func (store *DeadLetterFirestore) Put(ctx context.Context, record *BarkRecord) error {
+	docID := "deadLetters/" + record.ID
+	_, err := store.FirestoreClient.Doc(docID).Set(ctx, record)
+	return err
+}
+
+// Delete deletes a dead letter.
+func (store *DeadLetterFirestore) Delete(ctx context.Context, ID string) error {
+	docID := "deadLetters/" + ID
+	_, err := store.FirestoreClient.Doc(docID).Delete(ctx)
+	return err
+}
+
+// List returns a page of dead letters, most recent first. The page token is the ID of the last
+// dead letter of the previous page.
+func (store *DeadLetterFirestore) List(ctx context.Context, pageSize int,
+	pageToken string) ([]*BarkRecord, string, error) {
+
+	query := store.FirestoreClient.Collection("deadLetters").
+		OrderBy("actualTime", firestore.Desc).
+		Limit(pageSize)
+
+	// resume after last dead letter of previous page
+	if pageToken != "" {
+		lastDoc, err := store.FirestoreClient.Doc("deadLetters/" + pageToken).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, "", ErrInvalidPageToken
+		} else if err != nil {
+			return nil, "", err
+		}
+		query = query.StartAfter(lastDoc)
+	}
+
+	records, err := readBarkRecords(query.Documents(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextPageToken string
+	if len(records) == pageSize {
+		nextPageToken = records[len(records)-1].ID
+	}
+	return records, nextPageToken, nil
+}
@@ -0,0 +1,54 @@
+package dog
+
+import "time"
+
+// maxDeferrals bounds how many times a time may be deferred, as through consecutive windows.
+const maxDeferrals = 32
+
+// A Deferral moves times out of periods in which dogs do not bark. Defer returns t if it is not
+// within such a period, or the zero time if it cannot be deferred.
+type Deferral interface {
+	Defer(t time.Time) time.Time
+}
+
+// DeferredSchedule is a Schedule whose times are deferred until none of its deferrals moves them,
+// so that a time deferred out of one period never lands in another. A time that is still deferred
+// after maxDeferrals cannot be deferred, and the zero time is returned.
+type DeferredSchedule struct {
+	Schedule
+	Deferrals []Deferral
+}
+
+// Next implements the Schedule interface.
+func (s *DeferredSchedule) Next(t time.Time) time.Time {
+	next := s.Schedule.Next(t)
+	for i := 0; i < maxDeferrals && !next.IsZero(); i++ {
+		deferred := false
+		for _, deferral := range s.Deferrals {
+			if later := deferral.Defer(next); !later.Equal(next) {
+				next = later
+				deferred = true
+			}
+		}
+		if !deferred {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// withDeferral returns a Schedule that defers the times of s by d. If s is already deferred, d is
+// added to its deferrals rather than applied separately.
+func withDeferral(s Schedule, d Deferral) Schedule {
+	if deferred, ok := s.(*DeferredSchedule); ok {
+		deferrals := append([]Deferral{}, deferred.Deferrals...)
+		return &DeferredSchedule{
+			Schedule:  deferred.Schedule,
+			Deferrals: append(deferrals, d),
+		}
+	}
+	return &DeferredSchedule{
+		Schedule:  s,
+		Deferrals: []Deferral{d},
+	}
+}
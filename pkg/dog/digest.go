@@ -0,0 +1,461 @@
+package dog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BarkCollected is the outcome of a bark collected into a digest rather than delivered.
+const BarkCollected = "collected"
+
+// digestChannel is the channel recorded in the bark history for barks collected into a digest.
+const digestChannel = "digest"
+
+// ErrDigestEnded is returned when collecting a bark into a digest without upcoming deliveries.
+var ErrDigestEnded = errors.New("digest has no upcoming deliveries")
+
+// Digest documents must stay within the Firestore document size limit of 1 MiB, so the number of
+// pending entries and the text of each entry are bounded.
+const (
+	maxDigestEntries       = 200
+	maxDigestEntryTextSize = 2000
+)
+
+// A Digest accumulates the barks of dogs in digest mode and delivers them together as a single
+// grouped message on its own schedule.
+//
+// A dog is in digest mode when it references a digest, or when its user references a digest.
+type Digest struct {
+	ID              string          `json:"id" firestore:"id"`
+	UserID          string          `json:"userId,omitempty" firestore:"userId,omitempty"`
+	CreationTime    time.Time       `json:"creationTime" firestore:"creationTime"`
+	ScheduleType    string          `json:"scheduleType" firestore:"scheduleType"`
+	ScheduleRaw     json.RawMessage `json:"schedule" firestore:"schedule"`
+	Deliveries      []Delivery      `json:"deliveries,omitempty" firestore:"deliveries,omitempty"`
+	Pending         []DigestEntry   `json:"pending" firestore:"pending"`
+	NextTaskName    string          `json:"-" firestore:"nextTaskName"`
+	NextTaskTime    time.Time       `json:"-" firestore:"nextTaskTime"`
+	ClaimedTaskName string          `json:"-" firestore:"claimedTaskName"`
+	ClaimTime       time.Time       `json:"-" firestore:"claimTime"`
+}
+
+// A DigestEntry is a bark collected into a digest.
+type DigestEntry struct {
+	BarkID        string    `json:"barkId" firestore:"barkId"`
+	DogID         string    `json:"dogId" firestore:"dogId"`
+	IdeaID        string    `json:"ideaId" firestore:"ideaId"`
+	IdeaText      string    `json:"ideaText" firestore:"ideaText"`
+	ScheduledTime time.Time `json:"scheduledTime" firestore:"scheduledTime"`
+}
+
+// Schedule returns a Schedule based on the raw JSON in the Digest struct.
+func (d *Digest) Schedule() (Schedule, error) {
+	return ParseSchedule(d.ScheduleType, d.ScheduleRaw)
+}
+
+// bark returns a bark delivering entries of the digest as a single grouped message. The bark's
+// dog and idea describe the digest as a whole, for channels that do not format digests.
+func (d *Digest) bark(entries []DigestEntry) *Bark {
+	texts := make([]string, len(entries))
+	for i, entry := range entries {
+		texts[i] = "• " + entry.IdeaText
+	}
+
+	return &Bark{
+		ID: uuid.NewString(),
+		Dog: &Dog{
+			ID:           d.ID,
+			CreationTime: d.CreationTime,
+			UserID:       d.UserID,
+			ScheduleType: d.ScheduleType,
+			ScheduleRaw:  d.ScheduleRaw,
+			Deliveries:   d.Deliveries,
+		},
+		Idea: &bark.Idea{
+			Text:         strings.Join(texts, "\n"),
+			CreationTime: time.Now(),
+		},
+		TaskName:      d.NextTaskName,
+		ScheduledTime: d.NextTaskTime,
+		Attempt:       1,
+		DigestID:      d.ID,
+		Digest:        entries,
+	}
+}
+
+// appendDigestEntries adds entries to pending entries, skipping those already pending, and keeps
+// the maxDigestEntries most recently scheduled. It returns the entries and the number dropped.
+func appendDigestEntries(pending []DigestEntry, entries []DigestEntry) ([]DigestEntry, int) {
+	merged := make([]DigestEntry, 0, len(pending)+len(entries))
+	seen := make(map[string]bool, len(pending)+len(entries))
+	for _, list := range [][]DigestEntry{pending, entries} {
+		for _, entry := range list {
+			if seen[entry.BarkID] {
+				continue
+			}
+			seen[entry.BarkID] = true
+			merged = append(merged, entry)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].ScheduledTime.Before(merged[j].ScheduledTime)
+	})
+	dropped := 0
+	if len(merged) > maxDigestEntries {
+		dropped = len(merged) - maxDigestEntries
+		merged = merged[dropped:]
+	}
+	return merged, dropped
+}
+
+// truncateEntryText shortens text to at most maxDigestEntryTextSize bytes without splitting
+// characters.
+func truncateEntryText(text string) string {
+	if len(text) <= maxDigestEntryTextSize {
+		return text
+	}
+	end := maxDigestEntryTextSize - len("…")
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end] + "…"
+}
+
+// DigestStore is a data store for digests.
+type DigestStore interface {
+	Get(ctx context.Context, ID string) (*Digest, error)
+	Put(ctx context.Context, digest *Digest) error
+	// SetNextTask sets a digest's NextTask fields.
+	SetNextTask(ctx context.Context, digestID, taskName string, taskTime time.Time) error
+	Delete(ctx context.Context, ID string) error
+	// Append adds entries to a digest's pending entries, dropping the oldest beyond
+	// maxDigestEntries. It returns the number of entries dropped. ErrDigestEnded is returned if
+	// the digest has no next task.
+	Append(ctx context.Context, digestID string, entries ...DigestEntry) (int, error)
+	// Remove removes delivered entries from a digest's pending entries.
+	Remove(ctx context.Context, digestID string, entries ...DigestEntry) error
+	// ClaimTask claims a digest's next task for execution. The returned digest holds the pending
+	// entries, which remain pending until they are removed.
+	ClaimTask(ctx context.Context, digestID, taskName string) (*Digest, error)
+	ReleaseTask(ctx context.Context, digestID, taskName string) error
+}
+
+// CreateDigestRequest is the request type for creating a new Digest.
+type CreateDigestRequest struct {
+	UserID       string          `json:"userId,omitempty"`
+	ScheduleType string          `json:"scheduleType"`
+	Schedule     json.RawMessage `json:"schedule"`
+	Deliveries   []Delivery      `json:"deliveries,omitempty"`
+}
+
+// CreateDigestResponse is the response type for creating a new Digest. It holds the secrets
+// generated for the digest's deliveries, which are not returned again.
+type CreateDigestResponse struct {
+	*Digest
+	Secrets []DeliverySecret `json:"secrets,omitempty"`
+}
+
+var maxCreateDigestRequestSizeBytes int64 = 20000
+
+// PostDigest is a handler for creating a new Digest.
+func (service *Service) PostDigest(w http.ResponseWriter, r *http.Request) {
+	var requestBody CreateDigestRequest
+
+	// read request body into struct
+	r.Body = http.MaxBytesReader(w, r.Body, maxCreateDigestRequestSizeBytes)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		service.Logf(r, `result=DecodeError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	schedule, err := ParseSchedule(requestBody.ScheduleType, requestBody.Schedule)
+	if err != nil {
+		service.Logf(r, `result=ParseScheduleError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		service.Logf(r, `result=ScheduleExpiredError`)
+		bark.RespondError(w, http.StatusBadRequest, "schedule has no upcoming times")
+		return
+	}
+
+	// store schedule in its normalized form, with any defaults filled in
+	scheduleRaw, err := json.Marshal(schedule)
+	if err != nil {
+		service.Logf(r, `result=MarshalScheduleError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	// verify delivery channels exist
+	for _, delivery := range requestBody.Deliveries {
+		if _, err = service.Barkers.Get(delivery.Channel); err != nil {
+			service.Logf(r, `result=ChannelNotRegisteredError channel=%s`, delivery.Channel)
+			bark.RespondError(w, http.StatusBadRequest,
+				fmt.Sprint("delivery channel not registered: ", delivery.Channel))
+			return
+		}
+	}
+
+	secrets, err := service.Barkers.GenerateSecrets(requestBody.Deliveries)
+	if err != nil {
+		service.Logf(r, `action=GenerateSecrets result=InternalError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	// create digest
+	digest := &Digest{
+		ID:           uuid.NewString(),
+		UserID:       requestBody.UserID,
+		CreationTime: time.Now(),
+		ScheduleType: requestBody.ScheduleType,
+		ScheduleRaw:  scheduleRaw,
+		Deliveries:   requestBody.Deliveries,
+		Pending:      []DigestEntry{},
+	}
+
+	digest, err = service.TasksClient.RegisterDigest(r.Context(), digest)
+	if err != nil {
+		service.Logf(r, `action=RegisterDigest digestID=%s result=InternalError errorText="%s"`,
+			digest.ID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	service.Logf(r, `action=RegisterDigest digestID=%s result=OK`, digest.ID)
+
+	bark.RespondSuccess(w, http.StatusCreated, &CreateDigestResponse{Digest: digest, Secrets: secrets})
+}
+
+// GetDigest is a handler for getting a digest, including its pending entries.
+func (service *Service) GetDigest(w http.ResponseWriter, r *http.Request) {
+	digestID := chi.URLParam(r, "digestID")
+
+	digest, err := service.Digests.Get(r.Context(), digestID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetDigest digestID=%s result=OK`, digestID)
+		bark.RespondSuccess(w, http.StatusOK, digest)
+	case codes.NotFound:
+		service.Logf(r, `action=GetDigest digestID=%s result=NotFoundError`, digestID)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("digest not found with ID: ", digestID))
+	default:
+		service.Logf(r, `action=GetDigest digestID=%s result=InternalError errorText="%s"`,
+			digestID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+	}
+}
+
+// DeleteDigest is a handler for deleting a digest. Barks of dogs referencing a deleted digest are
+// delivered individually.
+func (service *Service) DeleteDigest(w http.ResponseWriter, r *http.Request) {
+	digestID := chi.URLParam(r, "digestID")
+
+	err := service.TasksClient.UnregisterDigest(r.Context(), digestID)
+	if err != nil {
+		service.Logf(r, `action=UnregisterDigest digestID=%s result=InternalError errorText="%s"`,
+			digestID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	service.Logf(r, `action=UnregisterDigest digestID=%s result=OK`, digestID)
+	bark.RespondSuccess(w, http.StatusNoContent, nil)
+}
+
+// SendDigest is a handler for delivering a digest's pending entries and scheduling its next
+// delivery. This endpoint is called by the tasks created for a digest.
+//
+// Entries remain pending until they are delivered, so if every delivery fails, or the delivery
+// ends before finishing, they are delivered with the digest's next delivery.
+func (service *Service) SendDigest(w http.ResponseWriter, r *http.Request) {
+	digestID := chi.URLParam(r, "digestID")
+
+	taskName := r.Header.Get(TaskNameHeader)
+	if taskName == "" {
+		service.Logf(r, `result=MissingTaskNameError`)
+		bark.RespondError(w, http.StatusBadRequest, "missing task name")
+		return
+	}
+
+	// claim task for execution
+	digest, err := service.TasksClient.ClaimDigest(r.Context(), digestID, taskName)
+	switch {
+	case err == nil:
+		service.Logf(r, `action=ClaimDigestTask digestID=%s taskName=%s entries=%d result=OK`,
+			digestID, taskName, len(digest.Pending))
+	case err == ErrTaskStale:
+		service.Logf(r, `action=ClaimDigestTask digestID=%s taskName=%s result=Ignored reason="%s"`,
+			digestID, taskName, err)
+		bark.RespondSuccess(w, http.StatusNoContent, nil)
+		return
+	case err == ErrTaskClaimed:
+		service.Logf(r, `action=ClaimDigestTask digestID=%s taskName=%s result=ConflictError`,
+			digestID, taskName)
+		bark.RespondError(w, http.StatusConflict, "task is being executed")
+		return
+	case status.Code(err) == codes.NotFound:
+		service.Logf(r, `action=ClaimDigestTask digestID=%s result=NotFoundError`, digestID)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("digest not found with ID: ", digestID))
+		return
+	default:
+		service.Logf(r, `action=ClaimDigestTask digestID=%s result=InternalError errorText="%s"`,
+			digestID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	entries := digest.Pending
+	b := digest.bark(entries)
+	digest.Pending = []DigestEntry{}
+
+	// schedule next delivery
+	digest, err = service.TasksClient.RescheduleDigest(r.Context(), digest)
+	if err != nil {
+		service.Logf(r, `action=RescheduleDigest digestID=%s result=InternalError errorText="%s"`,
+			digestID, err)
+		if err = service.Digests.ReleaseTask(r.Context(), digestID, taskName); err != nil {
+			service.Logf(r, `action=ReleaseDigestTask digestID=%s result=InternalError errorText="%s"`,
+				digestID, err)
+		}
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	service.Logf(r, `action=RescheduleDigest digestID=%s nextTaskTime=%s result=OK`,
+		digestID, digest.NextTaskTime.Format(time.RFC3339))
+
+	if len(entries) == 0 {
+		bark.RespondSuccess(w, http.StatusOK, digest)
+		return
+	}
+
+	// deliver digest to each of its channels
+	delivered := false
+	for _, delivery := range service.Barkers.Deliveries(b.Dog) {
+		b.Address = delivery.Address
+		b.Secret = delivery.Secret
+		barker, err := service.Barkers.Get(delivery.Channel)
+		if err == nil {
+			err = barker.Bark(r.Context(), b)
+		}
+		if err != nil {
+			service.Logf(r, `action=BarkDigest digestID=%s channel=%s entries=%d result=DeliveryError errorText="%s"`,
+				digestID, delivery.Channel, len(entries), err)
+			continue
+		}
+		service.Logf(r, `action=BarkDigest digestID=%s channel=%s entries=%d result=OK`,
+			digestID, delivery.Channel, len(entries))
+		delivered = true
+	}
+	if delivered {
+		service.removeDigestEntries(r, digestID, entries)
+	}
+
+	bark.RespondSuccess(w, http.StatusOK, digest)
+}
+
+// collect adds a bark to a digest instead of delivering it, and records it in the bark history.
+func (service *Service) collect(r *http.Request, digestID string, b *Bark) error {
+	dropped, err := service.Digests.Append(r.Context(), digestID, DigestEntry{
+		BarkID:        b.ID,
+		DogID:         b.Dog.ID,
+		IdeaID:        b.Idea.ID,
+		IdeaText:      truncateEntryText(b.Idea.Text),
+		ScheduledTime: b.ScheduledTime,
+	})
+	if err == ErrDigestEnded {
+		service.Logf(r, `action=CollectBark barkID=%s digestID=%s result=DigestEndedError`,
+			b.ID, digestID)
+		return err
+	} else if err != nil {
+		service.Logf(r, `action=CollectBark barkID=%s digestID=%s result=InternalError errorText="%s"`,
+			b.ID, digestID, err)
+		return err
+	}
+	service.Logf(r, `action=CollectBark barkID=%s digestID=%s dropped=%d result=OK`,
+		b.ID, digestID, dropped)
+
+	err = service.BarkStore.Put(r.Context(), &BarkRecord{
+		ID:            b.ID,
+		DogID:         b.Dog.ID,
+		IdeaID:        b.Idea.ID,
+		TaskName:      b.TaskName,
+		ScheduledTime: b.ScheduledTime,
+		ActualTime:    time.Now(),
+		Channel:       digestChannel,
+		Address:       digestID,
+		Attempt:       b.Attempt,
+		Outcome:       BarkCollected,
+	})
+	if err != nil {
+		service.Logf(r, `action=PutBarkRecord barkID=%s result=InternalError errorText="%s"`,
+			b.ID, err)
+	}
+	return nil
+}
+
+// digestID returns the ID of the digest that a dog's barks are collected into, if any.
+func (service *Service) digestID(r *http.Request, dog *Dog) string {
+	if dog.DigestID != "" || dog.UserID == "" {
+		return dog.DigestID
+	}
+
+	user, err := service.UserStore.Get(r.Context(), dog.UserID)
+	switch status.Code(err) {
+	case codes.OK:
+		return user.DigestID
+	case codes.NotFound:
+		return ""
+	default:
+		// deliver individually rather than lose the bark
+		service.Logf(r, `action=GetUser userID=%s result=InternalError errorText="%s"`,
+			dog.UserID, err)
+		return ""
+	}
+}
+
+// removeDigestEntries removes delivered entries from a digest's pending entries.
+func (service *Service) removeDigestEntries(r *http.Request, digestID string, entries []DigestEntry) {
+	err := service.Digests.Remove(r.Context(), digestID, entries...)
+	if err != nil {
+		service.Logf(r, `action=RemoveDigestEntries digestID=%s entries=%d result=InternalError errorText="%s"`,
+			digestID, len(entries), err)
+		return
+	}
+	service.Logf(r, `action=RemoveDigestEntries digestID=%s entries=%d result=OK`,
+		digestID, len(entries))
+}
+
+// digestExists verifies that a digest exists, responding with an error if it does not.
+func (service *Service) digestExists(w http.ResponseWriter, r *http.Request, digestID string) bool {
+	_, err := service.Digests.Get(r.Context(), digestID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetDigest digestID=%s result=OK`, digestID)
+		return true
+	case codes.NotFound:
+		service.Logf(r, `action=GetDigest digestID=%s result=NotFoundError`, digestID)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("digest not found with ID: ", digestID))
+	default:
+		service.Logf(r, `action=GetDigest digestID=%s result=InternalError errorText="%s"`,
+			digestID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+	}
+	return false
+}
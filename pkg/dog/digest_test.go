@@ -0,0 +1,206 @@
+package dog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAppendDigestEntries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	entry := func(i int) DigestEntry {
+		return DigestEntry{BarkID: fmt.Sprint("bark", i), ScheduledTime: start.Add(time.Duration(i) * time.Hour)}
+	}
+
+	// returned entries are older than pending ones, and duplicates are skipped
+	pending, dropped := appendDigestEntries([]DigestEntry{entry(2), entry(3)}, []DigestEntry{entry(1), entry(3)})
+	var IDs []string
+	for _, e := range pending {
+		IDs = append(IDs, e.BarkID)
+	}
+	if got := strings.Join(IDs, ","); got != "bark1,bark2,bark3" || dropped != 0 {
+		t.Errorf("appendDigestEntries() = %s, %d, want bark1,bark2,bark3, 0", got, dropped)
+	}
+
+	// the oldest entries are dropped beyond the limit
+	pending = nil
+	for i := 0; i < maxDigestEntries+5; i++ {
+		pending, _ = appendDigestEntries(pending, []DigestEntry{entry(i)})
+	}
+	pending, dropped = appendDigestEntries(pending, []DigestEntry{entry(-1), entry(1000)})
+	if len(pending) != maxDigestEntries || dropped != 2 {
+		t.Fatalf("appendDigestEntries() kept %d, dropped %d, want %d, 2", len(pending), dropped, maxDigestEntries)
+	}
+	if first, last := pending[0].BarkID, pending[len(pending)-1].BarkID; first != "bark6" || last != "bark1000" {
+		t.Errorf("kept entries from %s to %s, want bark6 to bark1000", first, last)
+	}
+}
+
+func TestTruncateEntryText(t *testing.T) {
+	for _, text := range []string{
+		"drink water",
+		strings.Repeat("a", maxDigestEntryTextSize),
+		strings.Repeat("a", maxDigestEntryTextSize+1),
+		strings.Repeat("吠", maxDigestEntryTextSize),
+		strings.Repeat("🐕", maxDigestEntryTextSize),
+	} {
+		got := truncateEntryText(text)
+		if len(got) > maxDigestEntryTextSize || !utf8.ValidString(got) {
+			t.Errorf("truncateEntryText() of %d bytes returned %d bytes, valid %v",
+				len(text), len(got), utf8.ValidString(got))
+		}
+		if len(text) <= maxDigestEntryTextSize && got != text {
+			t.Errorf("truncateEntryText() shortened text of %d bytes", len(text))
+		}
+	}
+}
+
+// memoryDigests is an in-memory DigestStore holding a single digest.
+type memoryDigests struct {
+	DigestStore
+	digest *Digest
+}
+
+func (store *memoryDigests) get(digestID string) (*Digest, error) {
+	if store.digest == nil || store.digest.ID != digestID {
+		return nil, status.Errorf(codes.NotFound, "digest not found with ID: %s", digestID)
+	}
+	return store.digest, nil
+}
+
+func (store *memoryDigests) ClaimTask(ctx context.Context, digestID, taskName string) (*Digest, error) {
+	digest, err := store.get(digestID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err = checkClaim(taskName, digest.NextTaskName, digest.ClaimedTaskName, digest.ClaimTime, now); err != nil {
+		return nil, err
+	}
+	digest.ClaimedTaskName = digest.NextTaskName
+	digest.ClaimTime = now
+	claimed := *digest
+	claimed.Pending = append([]DigestEntry(nil), digest.Pending...)
+	return &claimed, nil
+}
+
+func (store *memoryDigests) Remove(ctx context.Context, digestID string, entries ...DigestEntry) error {
+	digest, err := store.get(digestID)
+	if err != nil {
+		return err
+	}
+	removed := make(map[string]bool)
+	for _, entry := range entries {
+		removed[entry.BarkID] = true
+	}
+	var pending []DigestEntry
+	for _, entry := range digest.Pending {
+		if !removed[entry.BarkID] {
+			pending = append(pending, entry)
+		}
+	}
+	digest.Pending = pending
+	return nil
+}
+
+// digestTasks is a TasksClient that claims and reschedules the digests of a memoryDigests.
+type digestTasks struct {
+	TasksClient
+	digests *memoryDigests
+	tasks   int
+}
+
+func (tasks *digestTasks) ClaimDigest(ctx context.Context, digestID, taskName string) (*Digest, error) {
+	return tasks.digests.ClaimTask(ctx, digestID, taskName)
+}
+
+func (tasks *digestTasks) RescheduleDigest(ctx context.Context, digest *Digest) (*Digest, error) {
+	tasks.tasks++
+	tasks.digests.digest.NextTaskName = fmt.Sprint("task", tasks.tasks)
+	return digest, nil
+}
+
+func TestSendDigestAfterCrash(t *testing.T) {
+	digests := &memoryDigests{digest: &Digest{
+		ID:           "digest1",
+		Deliveries:   []Delivery{{Channel: "chat"}},
+		NextTaskName: "task0",
+		Pending: []DigestEntry{
+			{BarkID: "bark1", IdeaText: "drink water"},
+			{BarkID: "bark2", IdeaText: "stretch"},
+		},
+	}}
+	var deliveryErr error
+	var delivered []*Bark
+	barkers := &BarkerRegistry{}
+	barkers.Register("chat", BarkerFunc(func(ctx context.Context, b *Bark) error {
+		if deliveryErr != nil {
+			return deliveryErr
+		}
+		delivered = append(delivered, b)
+		return nil
+	}))
+	service := &Service{
+		TasksClient: &digestTasks{digests: digests},
+		Digests:     digests,
+		Barkers:     barkers,
+	}
+	router := chi.NewRouter()
+	service.RegisterRoutes(router)
+	send := func(taskName string) int {
+		req := httptest.NewRequest(http.MethodPost, "/digests/digest1/send", nil)
+		req.Header.Set(TaskNameHeader, taskName)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// an execution claims the task and ends without finishing
+	if _, err := digests.ClaimTask(context.Background(), "digest1", "task0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the redelivered task is retried while the claim is held, and the entries are kept
+	if code := send("task0"); code != http.StatusConflict {
+		t.Errorf("status while claimed = %d, want %d", code, http.StatusConflict)
+	}
+	if len(digests.digest.Pending) != 2 {
+		t.Fatalf("pending = %d entries, want 2", len(digests.digest.Pending))
+	}
+
+	// once the lease expires, the task is claimed again, and failed deliveries keep the entries
+	digests.digest.ClaimTime = digests.digest.ClaimTime.Add(-claimLease)
+	deliveryErr = errors.New("unreachable")
+	if code := send("task0"); code != http.StatusOK {
+		t.Errorf("status after lease = %d, want %d", code, http.StatusOK)
+	}
+	if len(digests.digest.Pending) != 2 || digests.digest.NextTaskName != "task1" {
+		t.Fatalf("pending = %d entries, next task %s, want 2 entries and task1",
+			len(digests.digest.Pending), digests.digest.NextTaskName)
+	}
+
+	// the replaced task is stale, and the next task delivers the kept entries
+	if code := send("task0"); code != http.StatusNoContent {
+		t.Errorf("status of stale task = %d, want %d", code, http.StatusNoContent)
+	}
+	deliveryErr = nil
+	if code := send("task1"); code != http.StatusOK {
+		t.Errorf("status of next task = %d, want %d", code, http.StatusOK)
+	}
+	if len(delivered) != 1 || len(delivered[0].Digest) != 2 {
+		t.Fatalf("delivered %d barks, want 1 of 2 entries", len(delivered))
+	}
+	if len(digests.digest.Pending) != 0 {
+		t.Errorf("pending = %d entries after delivery, want none", len(digests.digest.Pending))
+	}
+}
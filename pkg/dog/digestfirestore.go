@@ -0,0 +1,163 @@
+package dog
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// DigestFirestore is a Google Cloud Firestore-based data backend for digests.
+type DigestFirestore struct {
+	FirestoreClient *firestore.Client
+}
+
+// Get returns a digest by ID.
+func (store *DigestFirestore) Get(ctx context.Context, ID string) (*Digest, error) {
+	// get document from datastore
+	docID := "digests/" + ID
+	digestDoc, err := store.FirestoreClient.Doc(docID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert to digest
+	var digest Digest
+	err = digestDoc.DataTo(&digest)
+	return &digest, err
+}
+
+// Put inserts a digest.
+func (store *DigestFirestore) Put(ctx context.Context, digest *Digest) error {
+	docID := "digests/" + digest.ID
+	_, err := store.FirestoreClient.Doc(docID).Create(ctx, digest)
+	return err
+}
+
+// SetNextTask sets a digest's NextTask fields without modifying its pending entries.
+func (store *DigestFirestore) SetNextTask(ctx context.Context, digestID, taskName string,
+	taskTime time.Time) error {
+
+	docID := "digests/" + digestID
+	_, err := store.FirestoreClient.Doc(docID).Update(ctx, []firestore.Update{
+		{Path: "nextTaskName", Value: taskName},
+		{Path: "nextTaskTime", Value: taskTime},
+	})
+	return err
+}
+
+// Delete deletes a digest.
+func (store *DigestFirestore) Delete(ctx context.Context, ID string) error {
+	docID := "digests/" + ID
+	_, err := store.FirestoreClient.Doc(docID).Delete(ctx)
+	return err
+}
+
+// Append transactionally adds entries to a digest's pending entries. Entries already pending are
+// not duplicated, and the oldest entries beyond maxDigestEntries are dropped. ErrDigestEnded is
+// returned if the digest has no next task, since its entries would never be delivered.
+func (store *DigestFirestore) Append(ctx context.Context, digestID string,
+	entries ...DigestEntry) (int, error) {
+
+	docRef := store.FirestoreClient.Doc("digests/" + digestID)
+
+	var dropped int
+	err := store.FirestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		digestDoc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		var digest Digest
+		if err = digestDoc.DataTo(&digest); err != nil {
+			return err
+		}
+
+		if digest.NextTaskName == "" {
+			return ErrDigestEnded
+		}
+
+		var pending []DigestEntry
+		pending, dropped = appendDigestEntries(digest.Pending, entries)
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "pending", Value: pending},
+		})
+	})
+	return dropped, err
+}
+
+// Remove removes delivered entries from a digest's pending entries.
+func (store *DigestFirestore) Remove(ctx context.Context, digestID string,
+	entries ...DigestEntry) error {
+
+	values := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		values[i] = entry
+	}
+
+	docID := "digests/" + digestID
+	_, err := store.FirestoreClient.Doc(docID).Update(ctx, []firestore.Update{
+		{Path: "pending", Value: firestore.ArrayRemove(values...)},
+	})
+	return err
+}
+
+// ClaimTask transactionally claims a digest's next task for execution. The returned digest holds
+// the pending entries, which remain pending until they are removed. A claim may be taken again
+// once its lease has expired.
+func (store *DigestFirestore) ClaimTask(ctx context.Context, digestID,
+	taskName string) (*Digest, error) {
+
+	docRef := store.FirestoreClient.Doc("digests/" + digestID)
+
+	var digest *Digest
+	err := store.FirestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		digestDoc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		digest = new(Digest)
+		if err = digestDoc.DataTo(digest); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = checkClaim(taskName, digest.NextTaskName, digest.ClaimedTaskName, digest.ClaimTime, now)
+		if err != nil {
+			return err
+		}
+
+		digest.ClaimedTaskName = digest.NextTaskName
+		digest.ClaimTime = now
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "claimedTaskName", Value: digest.ClaimedTaskName},
+			{Path: "claimTime", Value: digest.ClaimTime},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// ReleaseTask transactionally releases a digest's claimed task if it is still claimed.
+func (store *DigestFirestore) ReleaseTask(ctx context.Context, digestID, taskName string) error {
+	docRef := store.FirestoreClient.Doc("digests/" + digestID)
+
+	return store.FirestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		digestDoc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		var digest Digest
+		if err = digestDoc.DataTo(&digest); err != nil {
+			return err
+		}
+
+		if digest.ClaimedTaskName == "" || !sameTask(digest.ClaimedTaskName, taskName) {
+			return nil
+		}
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "claimedTaskName", Value: ""},
+		})
+	})
+}
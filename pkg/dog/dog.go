@@ -2,6 +2,8 @@ package dog
 
 import (
 	"encoding/json"
+	"errors"
+	"path"
 	"time"
 )
 
@@ -9,22 +11,79 @@ import (
 //
 // A Dog is a schedule for an Idea or collection of Ideas to be "barked" to an end user.
 type Dog struct {
-	ID           string          `json:"id" firestore:"id"`
-	CreationTime time.Time       `json:"creationTime" firestore:"creationTime"`
-	IdeaID       string          `json:"ideaId,omitempty" firestore:"ideaId,omitempty"`
-	ScheduleType string          `json:"scheduleType" firestore:"scheduleType"`
-	ScheduleRaw  json.RawMessage `json:"schedule" firestore:"schedule"`
-	NextTaskName string          `json:"-" firestore:"nextTaskName"`
-	NextTaskTime time.Time       `json:"-" firestore:"nextTaskTime"`
+	ID              string          `json:"id" firestore:"id"`
+	CreationTime    time.Time       `json:"creationTime" firestore:"creationTime"`
+	IdeaID          string          `json:"ideaId,omitempty" firestore:"ideaId,omitempty"`
+	UserID          string          `json:"userId,omitempty" firestore:"userId,omitempty"`
+	ScheduleType    string          `json:"scheduleType" firestore:"scheduleType"`
+	ScheduleRaw     json.RawMessage `json:"schedule" firestore:"schedule"`
+	Deliveries      []Delivery      `json:"deliveries,omitempty" firestore:"deliveries,omitempty"`
+	QuietHours      QuietHours      `json:"quietHours,omitempty" firestore:"quietHours,omitempty"`
+	DigestID        string          `json:"digestId,omitempty" firestore:"digestId,omitempty"`
+	CalendarID      string          `json:"calendarId,omitempty" firestore:"calendarId,omitempty"`
+	Completed       bool            `json:"completed,omitempty" firestore:"completed"`
+	Repetition      *Repetition     `json:"repetition,omitempty" firestore:"repetition,omitempty"`
+	Bounds          *Bounds         `json:"bounds,omitempty" firestore:"bounds,omitempty"`
+	BarkCount       int             `json:"barkCount" firestore:"barkCount"`
+	NextTaskName    string          `json:"-" firestore:"nextTaskName"`
+	NextTaskTime    time.Time       `json:"-" firestore:"nextTaskTime"`
+	ClaimedTaskName string          `json:"-" firestore:"claimedTaskName"`
+	ClaimTime       time.Time       `json:"-" firestore:"claimTime"`
 
 	schedule Schedule
 }
 
-// Schedule returns a Schedule based on the raw JSON in the Dog struct.
+// Schedule returns a Schedule based on the raw JSON in the Dog struct, parsed as the dog's
+// schedule type.
+// The schedule is deferred by the dog's quiet hours. It is not limited by the dog's bounds, which
+// apply after all deferrals; see Whisperer.Schedule.
 func (d *Dog) Schedule() (Schedule, error) {
-	var err error = nil
 	if d.schedule == nil {
-		d.schedule, err = ParseSchedule("cron", d.ScheduleRaw)
+		schedule, err := ParseSchedule(d.ScheduleType, d.ScheduleRaw)
+		if err != nil {
+			return nil, err
+		}
+		if spaced, ok := schedule.(SpacedSchedule); ok {
+			spaced.Repetition = d.repetition(spaced)
+			schedule = spaced
+		}
+		d.schedule = d.QuietHours.Wrap(schedule)
 	}
-	return d.schedule, err
+	return d.schedule, nil
+}
+
+// repetition returns the spaced repetition state of a dog with a spaced schedule.
+func (d *Dog) repetition(s SpacedSchedule) Repetition {
+	if d.Repetition == nil {
+		return s.initial(d.CreationTime)
+	}
+	return *d.Repetition
+}
+
+// Errors returned when claiming a task for execution.
+var (
+	ErrTaskStale   = errors.New("task is not the dog's next task")
+	ErrTaskClaimed = errors.New("task already claimed")
+)
+
+// claimLease is how long a claim holds a task for its execution. A claim older than the lease is
+// taken to belong to an execution that ended without finishing or releasing the task, so the task
+// may be claimed again.
+const claimLease = 10 * time.Minute
+
+// checkClaim returns whether a task may be claimed at now, given the next and claimed task names
+// and claim time of its dog or digest.
+func checkClaim(taskName, nextTaskName, claimedTaskName string, claimTime, now time.Time) error {
+	if !sameTask(nextTaskName, taskName) {
+		return ErrTaskStale
+	} else if claimedTaskName == nextTaskName && now.Sub(claimTime) < claimLease {
+		return ErrTaskClaimed
+	}
+	return nil
+}
+
+// sameTask reports whether two task names refer to the same task. Each name may be either the full
+// name of the task or the short name sent by Cloud Tasks in request headers.
+func sameTask(a, b string) bool {
+	return path.Base(a) == path.Base(b)
 }
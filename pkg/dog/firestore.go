@@ -2,6 +2,7 @@ package dog
 
 import (
 	"context"
+	"time"
 
 	"cloud.google.com/go/firestore"
 )
@@ -33,9 +34,115 @@ func (store *DoggoFirestore) Put(ctx context.Context, dog *Dog) error {
 	return err
 }
 
+// Update sets fields of an existing dog by their firestore names, leaving its other fields as
+// they are. A NotFound error is returned if the dog does not exist.
+func (store *DoggoFirestore) Update(ctx context.Context, dogID string, fields map[string]interface{}) error {
+	docID := "dogs/" + dogID
+	_, err := store.FirestoreClient.Doc(docID).Update(ctx, fieldUpdates(fields))
+	return err
+}
+
+// Modify transactionally applies modify to a dog and sets the fields that modify returns, by their
+// firestore names. The modified dog is returned. An error returned by modify aborts the
+// transaction and is returned as is.
+func (store *DoggoFirestore) Modify(ctx context.Context, dogID string,
+	modify func(dog *Dog) (map[string]interface{}, error)) (*Dog, error) {
+
+	docRef := store.FirestoreClient.Doc("dogs/" + dogID)
+
+	var dog *Dog
+	err := store.FirestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		dogDoc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		dog = new(Dog)
+		if err = dogDoc.DataTo(dog); err != nil {
+			return err
+		}
+
+		fields, err := modify(dog)
+		if err != nil {
+			return err
+		}
+		return tx.Update(docRef, fieldUpdates(fields))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dog, nil
+}
+
 // Delete deletes an dog.
 func (store *DoggoFirestore) Delete(ctx context.Context, ID string) error {
 	docID := "dogs/" + ID
 	_, err := store.FirestoreClient.Doc(docID).Delete(ctx)
 	return err
 }
+
+// ClaimTask transactionally claims a dog's next task for execution. The task name may be the full
+// or short name of the task. A claim may be taken again once its lease has expired.
+func (store *DoggoFirestore) ClaimTask(ctx context.Context, dogID, taskName string) (*Dog, error) {
+	docRef := store.FirestoreClient.Doc("dogs/" + dogID)
+
+	var dog *Dog
+	err := store.FirestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		dogDoc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		dog = new(Dog)
+		if err = dogDoc.DataTo(dog); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = checkClaim(taskName, dog.NextTaskName, dog.ClaimedTaskName, dog.ClaimTime, now)
+		if err != nil {
+			return err
+		}
+
+		dog.ClaimedTaskName = dog.NextTaskName
+		dog.ClaimTime = now
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "claimedTaskName", Value: dog.ClaimedTaskName},
+			{Path: "claimTime", Value: dog.ClaimTime},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dog, nil
+}
+
+// ReleaseTask transactionally releases a dog's claimed task if it is still claimed.
+func (store *DoggoFirestore) ReleaseTask(ctx context.Context, dogID, taskName string) error {
+	docRef := store.FirestoreClient.Doc("dogs/" + dogID)
+
+	return store.FirestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		dogDoc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		var dog Dog
+		if err = dogDoc.DataTo(&dog); err != nil {
+			return err
+		}
+
+		if dog.ClaimedTaskName == "" || !sameTask(dog.ClaimedTaskName, taskName) {
+			return nil
+		}
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "claimedTaskName", Value: ""},
+		})
+	})
+}
+
+// fieldUpdates converts field values by firestore name to firestore updates.
+func fieldUpdates(fields map[string]interface{}) []firestore.Update {
+	updates := make([]firestore.Update, 0, len(fields))
+	for path, value := range fields {
+		updates = append(updates, firestore.Update{Path: path, Value: value})
+	}
+	return updates
+}
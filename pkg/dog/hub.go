@@ -0,0 +1,50 @@
+package dog
+
+import "sync"
+
+// barkHubBufferSize is the number of records buffered for each hub subscriber.
+const barkHubBufferSize = 16
+
+// BarkHub fans out delivered bark records to subscribers. It is safe for concurrent use.
+//
+// A hub only sees the barks delivered by its own instance of the service, so it is a local cache
+// in front of the bark history rather than the source of a stream. Records are dropped for
+// subscribers that do not keep up, which receive them from the bark history instead.
+type BarkHub struct {
+	mu          sync.Mutex
+	subscribers map[chan *BarkRecord]struct{}
+}
+
+// Subscribe returns a channel of published records and a function that ends the subscription.
+func (hub *BarkHub) Subscribe() (<-chan *BarkRecord, func()) {
+	ch := make(chan *BarkRecord, barkHubBufferSize)
+
+	hub.mu.Lock()
+	if hub.subscribers == nil {
+		hub.subscribers = make(map[chan *BarkRecord]struct{})
+	}
+	hub.subscribers[ch] = struct{}{}
+	hub.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			hub.mu.Lock()
+			delete(hub.subscribers, ch)
+			hub.mu.Unlock()
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Publish sends a record to all subscribers without blocking.
+func (hub *BarkHub) Publish(record *BarkRecord) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for ch := range hub.subscribers {
+		select {
+		case ch <- record:
+		default:
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package dog
+
+import "testing"
+
+func TestBarkHub(t *testing.T) {
+	var hub BarkHub
+	first, unsubscribeFirst := hub.Subscribe()
+	second, unsubscribeSecond := hub.Subscribe()
+	defer unsubscribeSecond()
+
+	hub.Publish(&BarkRecord{ID: "bark1"})
+	for _, ch := range []<-chan *BarkRecord{first, second} {
+		if record := <-ch; record.ID != "bark1" {
+			t.Errorf("received %s, want bark1", record.ID)
+		}
+	}
+
+	// unsubscribed channels receive nothing, and unsubscribing twice is harmless
+	unsubscribeFirst()
+	unsubscribeFirst()
+	hub.Publish(&BarkRecord{ID: "bark2"})
+	select {
+	case record := <-first:
+		t.Errorf("unsubscribed channel received %s", record.ID)
+	default:
+	}
+
+	// publishing to a subscriber that does not keep up drops records instead of blocking
+	for i := 0; i < 2*barkHubBufferSize; i++ {
+		hub.Publish(&BarkRecord{ID: "flood"})
+	}
+	if n := len(second); n != barkHubBufferSize {
+		t.Errorf("buffered %d records, want %d", n, barkHubBufferSize)
+	}
+}
@@ -0,0 +1,220 @@
+package dog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+// maxHolidayYears is how many years ahead recurring holidays are expanded when imported.
+const maxHolidayYears = 5
+
+// maxHolidays is the most holidays that may be imported into a calendar.
+const maxHolidays = 5000
+
+// icsEvent holds the properties of an iCalendar event that are used for holidays.
+type icsEvent struct {
+	start, end time.Time
+	allDay     bool
+	name       string
+	rule       string
+	exdates    map[string]bool
+	cancelled  bool
+}
+
+// ParseICS parses the events of an iCalendar file as holidays, sorted by date. Each event
+// excludes the dates it covers. Recurring events are expanded until horizon.
+func ParseICS(r io.Reader, horizon time.Time) ([]Holiday, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	var event *icsEvent
+	for _, line := range lines {
+		name, params, value, err := parseICSProperty(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = &icsEvent{exdates: make(map[string]bool)}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if event == nil || event.start.IsZero() {
+				return nil, errors.New("event without DTSTART")
+			}
+			if !event.cancelled {
+				if err = event.addDates(names, horizon); err != nil {
+					return nil, err
+				}
+			}
+			event = nil
+		case event == nil:
+			// properties outside of events are ignored
+		case name == "DTSTART":
+			event.start, event.allDay, err = parseICSDate(params, value)
+		case name == "DTEND":
+			event.end, _, err = parseICSDate(params, value)
+		case name == "SUMMARY":
+			event.name = unescapeICS(value)
+		case name == "RRULE":
+			event.rule = value
+		case name == "EXDATE":
+			for _, exdate := range strings.Split(value, ",") {
+				var t time.Time
+				if t, _, err = parseICSDate(params, exdate); err == nil {
+					event.exdates[t.Format("2006-01-02")] = true
+				}
+			}
+		case name == "STATUS":
+			event.cancelled = strings.EqualFold(value, "CANCELLED")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > maxHolidays {
+			return nil, fmt.Errorf("calendar has more than %d holidays", maxHolidays)
+		}
+	}
+
+	holidays := make([]Holiday, 0, len(names))
+	for date, name := range names {
+		holidays = append(holidays, Holiday{Date: date, Name: name})
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+	return holidays, nil
+}
+
+// addDates adds the dates covered by each occurrence of the event to names, keyed by date.
+func (event *icsEvent) addDates(names map[string]string, horizon time.Time) error {
+	// all-day events end on their exclusive end date, other events cover their start date
+	days := 1
+	if event.allDay && event.end.After(event.start) {
+		days = int(event.end.Sub(event.start).Hours()/24 + 0.5)
+	}
+	if days > maxExcludedDays {
+		return fmt.Errorf("event is longer than %d days: %s", maxExcludedDays, event.name)
+	}
+
+	if event.rule == "" {
+		event.addOccurrence(names, event.start, days)
+		return nil
+	}
+
+	option, err := rrule.StrToROption(event.rule)
+	if err != nil {
+		return fmt.Errorf("invalid RRULE: %s", event.rule)
+	}
+	if option.Freq > rrule.DAILY {
+		return fmt.Errorf("RRULE must not recur more than daily: %s", event.rule)
+	}
+
+	// occurrences are generated one at a time and only until the horizon, so that a rule without
+	// an end cannot expand without bound
+	option.Dtstart = event.start
+	if option.Until.IsZero() || option.Until.After(horizon) {
+		option.Until = horizon
+	}
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return fmt.Errorf("invalid RRULE: %s", event.rule)
+	}
+	next := rule.Iterator()
+	for start, ok := next(); ok && len(names) <= maxHolidays; start, ok = next() {
+		event.addOccurrence(names, start, days)
+	}
+	return nil
+}
+
+// addOccurrence adds the dates of an occurrence of the event starting at start to names, unless
+// the occurrence is excluded.
+func (event *icsEvent) addOccurrence(names map[string]string, start time.Time, days int) {
+	if event.exdates[start.Format("2006-01-02")] {
+		return
+	}
+	for i := 0; i < days; i++ {
+		date := start.AddDate(0, 0, i).Format("2006-01-02")
+		if _, found := names[date]; !found {
+			names[date] = event.name
+		}
+	}
+}
+
+// unfoldICS reads the content lines of an iCalendar file, joining folded lines.
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if len(lines) > 0 {
+				lines[len(lines)-1] += line[1:]
+			}
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, errors.New("not an iCalendar file")
+	}
+	return lines, nil
+}
+
+// parseICSProperty splits a content line into its upper case name, its parameters and its value.
+func parseICSProperty(line string) (string, map[string]string, string, error) {
+	// the value starts at the first colon outside of a quoted parameter value
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("invalid iCalendar line: %s", line)
+	}
+
+	fields := strings.Split(line[:colon], ";")
+	params := make(map[string]string)
+	for _, param := range fields[1:] {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(fields[0]), params, line[colon+1:], nil
+}
+
+// parseICSDate parses the date of an iCalendar DATE or DATE-TIME value, reporting whether it is
+// a DATE. Since holidays are whole days, the date is returned at midnight UTC.
+func parseICSDate(params map[string]string, value string) (time.Time, bool, error) {
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("invalid iCalendar date: %s", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid iCalendar date: %s", value)
+	}
+	allDay := params["VALUE"] == "DATE" || len(value) == 8
+	return date, allDay, nil
+}
+
+// unescapeICS unescapes an iCalendar TEXT value.
+func unescapeICS(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
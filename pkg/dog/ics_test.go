@@ -0,0 +1,96 @@
+package dog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICS(t *testing.T) {
+	horizon := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		events string
+		want   []Holiday
+	}{
+		{
+			name: "all-day event",
+			events: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240704\nDTEND;VALUE=DATE:20240705\n" +
+				"SUMMARY:Independence Day\nEND:VEVENT\n",
+			want: []Holiday{{Date: "2024-07-04", Name: "Independence Day"}},
+		},
+		{
+			name: "multi-day event",
+			events: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20241224\nDTEND;VALUE=DATE:20241227\n" +
+				"SUMMARY:Winter break\nEND:VEVENT\n",
+			want: []Holiday{
+				{Date: "2024-12-24", Name: "Winter break"},
+				{Date: "2024-12-25", Name: "Winter break"},
+				{Date: "2024-12-26", Name: "Winter break"},
+			},
+		},
+		{
+			name: "timed event in quoted time zone",
+			events: "BEGIN:VEVENT\nDTSTART;TZID=\"America/New_York\":20240101T090000\n" +
+				"SUMMARY:New Year\\, observed\nEND:VEVENT\n",
+			want: []Holiday{{Date: "2024-01-01", Name: "New Year, observed"}},
+		},
+		{
+			name: "yearly rule until horizon",
+			events: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20231225\nRRULE:FREQ=YEARLY\n" +
+				"EXDATE;VALUE=DATE:20241225\nSUMMARY:Christmas\nEND:VEVENT\n",
+			want: []Holiday{
+				{Date: "2023-12-25", Name: "Christmas"},
+				{Date: "2025-12-25", Name: "Christmas"},
+			},
+		},
+		{
+			name: "cancelled event",
+			events: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240101\nSTATUS:CANCELLED\n" +
+				"SUMMARY:Cancelled\nEND:VEVENT\n",
+			want: []Holiday{},
+		},
+		{
+			name: "folded summary",
+			events: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240527\nSUMMARY:Memorial\n" +
+				"  Day\nEND:VEVENT\n",
+			want: []Holiday{{Date: "2024-05-27", Name: "Memorial Day"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ics := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+				strings.ReplaceAll(test.events, "\n", "\r\n") + "END:VCALENDAR\r\n"
+			got, err := ParseICS(strings.NewReader(ics), horizon)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseICS() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseICSErrors(t *testing.T) {
+	horizon := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]string{
+		"not a calendar":     "BEGIN:VEVENT\nEND:VEVENT\n",
+		"missing start":      "BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VEVENT\nEND:VCALENDAR\n",
+		"invalid date":       "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:2024\nEND:VEVENT\nEND:VCALENDAR\n",
+		"invalid rule":       "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240101\nRRULE:FREQ=NEVER\nEND:VEVENT\nEND:VCALENDAR\n",
+		"sub-daily rule":     "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240101T000000Z\nRRULE:FREQ=HOURLY\nEND:VEVENT\nEND:VCALENDAR\n",
+		"too many holidays":  "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240101\nRRULE:FREQ=DAILY\nEND:VEVENT\nEND:VCALENDAR\n",
+		"line without colon": "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART\nEND:VEVENT\nEND:VCALENDAR\n",
+	}
+
+	for name, ics := range tests {
+		if _, err := ParseICS(strings.NewReader(ics), horizon); err == nil {
+			t.Errorf("%s: ParseICS() succeeded, want error", name)
+		}
+	}
+}
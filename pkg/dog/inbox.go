@@ -0,0 +1,228 @@
+package dog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Inbox item states.
+const (
+	InboxUnread       = "unread"
+	InboxAcknowledged = "acknowledged"
+	InboxSnoozed      = "snoozed"
+)
+
+// Limits on how long an inbox item may be snoozed. Follow-up tasks cannot be scheduled more
+// than 30 days ahead.
+const (
+	minSnoozeDuration = time.Minute
+	maxSnoozeDuration = 30 * 24 * time.Hour
+)
+
+// An InboxItem is a bark delivered to a user's inbox. Its ID is the ID of the bark.
+type InboxItem struct {
+	ID           string     `json:"id" firestore:"id"`
+	UserID       string     `json:"userId" firestore:"userId"`
+	DogID        string     `json:"dogId" firestore:"dogId"`
+	IdeaID       string     `json:"ideaId" firestore:"ideaId"`
+	IdeaText     string     `json:"ideaText" firestore:"ideaText"`
+	State        string     `json:"state" firestore:"state"`
+	ReceivedTime time.Time  `json:"receivedTime" firestore:"receivedTime"`
+	AckTime      *time.Time `json:"ackTime,omitempty" firestore:"ackTime,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozedUntil,omitempty" firestore:"snoozedUntil,omitempty"`
+}
+
+// InboxStore is a data store for inbox items.
+type InboxStore interface {
+	Get(ctx context.Context, ID string) (*InboxItem, error)
+	// Set writes an inbox item, replacing any existing item with the same key.
+	Set(ctx context.Context, item *InboxItem) error
+	// Modify transactionally applies modify to an inbox item and writes the modified item, which
+	// is returned. An error returned by modify aborts the transaction and is returned as is.
+	Modify(ctx context.Context, ID string, modify func(item *InboxItem) error) (*InboxItem, error)
+	// ListByUser returns a page of a user's inbox items in a given state, most recently received
+	// first, and the token for the next page. The next page token is empty when there are no more
+	// items.
+	ListByUser(ctx context.Context, userID, state string, pageSize int,
+		pageToken string) ([]*InboxItem, string, error)
+}
+
+// errFollowUpStale is returned when resurfacing an inbox item that was acknowledged or snoozed
+// again since its follow-up was scheduled.
+var errFollowUpStale = errors.New("follow-up is stale")
+
+// ListInboxResponse is the response type for listing a user's inbox.
+type ListInboxResponse struct {
+	Items         []*InboxItem `json:"items"`
+	NextPageToken string       `json:"nextPageToken,omitempty"`
+}
+
+// GetInbox is a handler for listing a user's inbox items. The user is given by the userId query
+// parameter, and the state query parameter selects the items to list, defaulting to unread.
+// Results are paginated with the pageSize and pageToken query parameters.
+func (service *Service) GetInbox(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("userId")
+	if userID == "" {
+		service.Logf(r, `result=MissingUserIDError`)
+		bark.RespondError(w, http.StatusBadRequest, "userId is required")
+		return
+	}
+	state := r.URL.Query().Get("state")
+	switch state {
+	case "":
+		state = InboxUnread
+	case InboxUnread, InboxAcknowledged, InboxSnoozed:
+	default:
+		service.Logf(r, `result=InvalidStateError state=%s`, state)
+		bark.RespondError(w, http.StatusBadRequest, fmt.Sprint("invalid inbox state: ", state))
+		return
+	}
+
+	pageSize, pageToken, err := pageParams(r)
+	if err != nil {
+		service.Logf(r, `result=InvalidPageSizeError`)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, nextPageToken, err := service.Inbox.ListByUser(r.Context(), userID, state, pageSize, pageToken)
+	switch err {
+	case nil:
+		service.Logf(r, `action=ListInbox userID=%s state=%s count=%d result=OK`,
+			userID, state, len(items))
+		bark.RespondSuccess(w, http.StatusOK, &ListInboxResponse{
+			Items:         items,
+			NextPageToken: nextPageToken,
+		})
+	case ErrInvalidPageToken:
+		service.Logf(r, `action=ListInbox userID=%s state=%s result=InvalidPageTokenError`,
+			userID, state)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+	default:
+		service.Logf(r, `action=ListInbox userID=%s state=%s result=InternalError errorText="%s"`,
+			userID, state, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+	}
+}
+
+// AckInboxItem is a handler for acknowledging that an inbox item was seen.
+func (service *Service) AckInboxItem(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	service.modifyInboxItem(w, r, func(item *InboxItem) error {
+		item.State = InboxAcknowledged
+		item.AckTime = &now
+		item.SnoozedUntil = nil
+		return nil
+	})
+}
+
+// SnoozeInboxItem is a handler for deferring an inbox item. The for query parameter is the
+// snooze duration, e.g. "1h". The item returns to the unread state when the snooze ends.
+func (service *Service) SnoozeInboxItem(w http.ResponseWriter, r *http.Request) {
+	snoozeFor, err := time.ParseDuration(r.URL.Query().Get("for"))
+	if err != nil || snoozeFor < minSnoozeDuration || snoozeFor > maxSnoozeDuration {
+		service.Logf(r, `result=InvalidSnoozeDurationError`)
+		bark.RespondError(w, http.StatusBadRequest,
+			fmt.Sprintf("for must be a duration between %s and %s", minSnoozeDuration, maxSnoozeDuration))
+		return
+	}
+
+	item, ok := service.getInboxItem(w, r)
+	if !ok {
+		return
+	}
+
+	// schedule the follow-up first, so that a snoozed item always resurfaces; follow-ups of items
+	// that are not snoozed until their time are ignored
+	snoozedUntil := time.Now().Add(snoozeFor)
+	err = service.TasksClient.ScheduleFollowUp(r.Context(), item, snoozedUntil)
+	if err != nil {
+		service.Logf(r, `action=ScheduleFollowUp barkID=%s result=InternalError errorText="%s"`,
+			item.ID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	service.Logf(r, `action=ScheduleFollowUp barkID=%s followUpTime=%s result=OK`,
+		item.ID, snoozedUntil.Format(time.RFC3339))
+
+	service.modifyInboxItem(w, r, func(item *InboxItem) error {
+		item.State = InboxSnoozed
+		item.SnoozedUntil = &snoozedUntil
+		return nil
+	})
+}
+
+// ResurfaceInboxItem is a handler for ending the snooze of an inbox item.
+// This endpoint is called by the follow-up tasks created when snoozing.
+func (service *Service) ResurfaceInboxItem(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(TaskNameHeader) == "" {
+		service.Logf(r, `result=MissingTaskNameError`)
+		bark.RespondError(w, http.StatusBadRequest, "missing task name")
+		return
+	}
+
+	now := time.Now()
+	service.modifyInboxItem(w, r, func(item *InboxItem) error {
+		// ignore follow-ups of items acknowledged or snoozed again since
+		if item.State != InboxSnoozed || item.SnoozedUntil == nil || item.SnoozedUntil.Sub(now) > time.Minute {
+			return errFollowUpStale
+		}
+		item.State = InboxUnread
+		item.ReceivedTime = now
+		item.SnoozedUntil = nil
+		return nil
+	})
+}
+
+// getInboxItem gets the inbox item of the request, responding with an error if it cannot.
+func (service *Service) getInboxItem(w http.ResponseWriter, r *http.Request) (*InboxItem, bool) {
+	barkID := chi.URLParam(r, "barkID")
+
+	item, err := service.Inbox.Get(r.Context(), barkID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetInboxItem barkID=%s result=OK`, barkID)
+		return item, true
+	case codes.NotFound:
+		service.Logf(r, `action=GetInboxItem barkID=%s result=NotFoundError`, barkID)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("inbox item not found with ID: ", barkID))
+	default:
+		service.Logf(r, `action=GetInboxItem barkID=%s result=InternalError errorText="%s"`,
+			barkID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+	}
+	return nil, false
+}
+
+// modifyInboxItem transactionally modifies the inbox item of the request and responds with it.
+// Stale follow-ups are ignored.
+func (service *Service) modifyInboxItem(w http.ResponseWriter, r *http.Request,
+	modify func(item *InboxItem) error) {
+
+	barkID := chi.URLParam(r, "barkID")
+
+	item, err := service.Inbox.Modify(r.Context(), barkID, modify)
+	switch {
+	case err == nil:
+		service.Logf(r, `action=ModifyInboxItem barkID=%s state=%s result=OK`, barkID, item.State)
+		bark.RespondSuccess(w, http.StatusOK, item)
+	case err == errFollowUpStale:
+		service.Logf(r, `action=ModifyInboxItem barkID=%s result=Ignored`, barkID)
+		bark.RespondSuccess(w, http.StatusNoContent, nil)
+	case status.Code(err) == codes.NotFound:
+		service.Logf(r, `action=ModifyInboxItem barkID=%s result=NotFoundError`, barkID)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("inbox item not found with ID: ", barkID))
+	default:
+		service.Logf(r, `action=ModifyInboxItem barkID=%s result=InternalError errorText="%s"`,
+			barkID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+	}
+}
@@ -0,0 +1,250 @@
+package dog_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/dog"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memoryInbox is an in-memory InboxStore.
+type memoryInbox struct {
+	items map[string]*dog.InboxItem
+}
+
+func (inbox *memoryInbox) Get(ctx context.Context, ID string) (*dog.InboxItem, error) {
+	item, found := inbox.items[ID]
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "inbox item not found with ID: %s", ID)
+	}
+	copied := *item
+	return &copied, nil
+}
+
+func (inbox *memoryInbox) Set(ctx context.Context, item *dog.InboxItem) error {
+	copied := *item
+	inbox.items[item.ID] = &copied
+	return nil
+}
+
+func (inbox *memoryInbox) Modify(ctx context.Context, ID string,
+	modify func(item *dog.InboxItem) error) (*dog.InboxItem, error) {
+
+	item, err := inbox.Get(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = modify(item); err != nil {
+		return nil, err
+	}
+	return item, inbox.Set(ctx, item)
+}
+
+func (inbox *memoryInbox) ListByUser(ctx context.Context, userID, state string, pageSize int,
+	pageToken string) ([]*dog.InboxItem, string, error) {
+
+	var items []*dog.InboxItem
+	for _, item := range inbox.items {
+		if item.UserID == userID && item.State == state {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ReceivedTime.After(items[j].ReceivedTime) })
+
+	if pageToken != "" {
+		start := -1
+		for i, item := range items {
+			if item.ID == pageToken {
+				start = i + 1
+			}
+		}
+		if start < 0 {
+			return nil, "", dog.ErrInvalidPageToken
+		}
+		items = items[start:]
+	}
+	if len(items) < pageSize {
+		return items, "", nil
+	}
+	return items[:pageSize], items[pageSize-1].ID, nil
+}
+
+// followUpTasks is a memoryTasks that records scheduled follow-ups.
+type followUpTasks struct {
+	memoryTasks
+	followUps map[string]time.Time
+}
+
+func (tasks *followUpTasks) ScheduleFollowUp(ctx context.Context, item *dog.InboxItem, scheduleTime time.Time) error {
+	tasks.followUps[item.ID] = scheduleTime
+	return nil
+}
+
+func TestInboxItemActions(t *testing.T) {
+	soon := time.Now().Add(30 * time.Second)
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		item         dog.InboxItem
+		action       string
+		wantCode     int
+		wantState    string
+		wantFollowUp time.Duration
+	}{
+		{
+			name:      "ack",
+			item:      dog.InboxItem{State: dog.InboxUnread},
+			action:    "ack",
+			wantCode:  http.StatusOK,
+			wantState: dog.InboxAcknowledged,
+		},
+		{
+			name:      "ack snoozed",
+			item:      dog.InboxItem{State: dog.InboxSnoozed, SnoozedUntil: &later},
+			action:    "ack",
+			wantCode:  http.StatusOK,
+			wantState: dog.InboxAcknowledged,
+		},
+		{
+			name:         "snooze",
+			item:         dog.InboxItem{State: dog.InboxUnread},
+			action:       "snooze?for=2h",
+			wantCode:     http.StatusOK,
+			wantState:    dog.InboxSnoozed,
+			wantFollowUp: 2 * time.Hour,
+		},
+		{
+			name:      "invalid snooze",
+			item:      dog.InboxItem{State: dog.InboxUnread},
+			action:    "snooze?for=1s",
+			wantCode:  http.StatusBadRequest,
+			wantState: dog.InboxUnread,
+		},
+		{
+			name:      "resurface",
+			item:      dog.InboxItem{State: dog.InboxSnoozed, SnoozedUntil: &soon},
+			action:    "resurface",
+			wantCode:  http.StatusOK,
+			wantState: dog.InboxUnread,
+		},
+		{
+			name:      "stale resurface of snoozed again",
+			item:      dog.InboxItem{State: dog.InboxSnoozed, SnoozedUntil: &later},
+			action:    "resurface",
+			wantCode:  http.StatusNoContent,
+			wantState: dog.InboxSnoozed,
+		},
+		{
+			name:      "stale resurface of acknowledged",
+			item:      dog.InboxItem{State: dog.InboxAcknowledged},
+			action:    "resurface",
+			wantCode:  http.StatusNoContent,
+			wantState: dog.InboxAcknowledged,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := test.item
+			item.ID = "bark1"
+			item.UserID = "user1"
+			inbox := &memoryInbox{items: map[string]*dog.InboxItem{"bark1": &item}}
+			tasks := &followUpTasks{followUps: make(map[string]time.Time)}
+			service := &dog.Service{TasksClient: tasks, Inbox: inbox}
+
+			router := chi.NewRouter()
+			service.RegisterRoutes(router)
+			req := httptest.NewRequest(http.MethodPost, "/inbox/bark1/"+test.action, nil)
+			req.Header.Set(dog.TaskNameHeader, "followup-bark1")
+			w := httptest.NewRecorder()
+			start := time.Now()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantCode, w.Body)
+			}
+			stored := inbox.items["bark1"]
+			if stored.State != test.wantState {
+				t.Errorf("state = %s, want %s", stored.State, test.wantState)
+			}
+			if w.Code == http.StatusOK {
+				var responded dog.InboxItem
+				json.NewDecoder(w.Body).Decode(&responded)
+				if responded.State != test.wantState {
+					t.Errorf("responded state = %s, want %s", responded.State, test.wantState)
+				}
+			}
+
+			followUp, scheduled := tasks.followUps["bark1"]
+			if test.wantFollowUp == 0 {
+				if scheduled {
+					t.Errorf("follow-up scheduled at %s, want none", followUp)
+				}
+				return
+			}
+			if !scheduled || followUp.Before(start.Add(test.wantFollowUp)) || followUp.After(time.Now().Add(test.wantFollowUp)) {
+				t.Errorf("follow-up = %s, want in %s", followUp, test.wantFollowUp)
+			}
+			if stored.SnoozedUntil == nil || !stored.SnoozedUntil.Equal(followUp) {
+				t.Errorf("snoozed until %v, want the follow-up time %s", stored.SnoozedUntil, followUp)
+			}
+		})
+	}
+}
+
+func TestGetInbox(t *testing.T) {
+	inbox := &memoryInbox{items: make(map[string]*dog.InboxItem)}
+	received := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for _, ID := range []string{"bark1", "bark2", "bark3"} {
+		received = received.Add(time.Hour)
+		inbox.Set(context.Background(), &dog.InboxItem{
+			ID: ID, UserID: "user1", State: dog.InboxUnread, ReceivedTime: received,
+		})
+	}
+	service := &dog.Service{Inbox: inbox}
+
+	tests := []struct {
+		name          string
+		query         string
+		wantCode      int
+		wantItems     int
+		wantPageToken string
+	}{
+		{name: "first page", query: "userId=user1&pageSize=2", wantCode: http.StatusOK, wantItems: 2, wantPageToken: "bark2"},
+		{name: "last page", query: "userId=user1&pageSize=2&pageToken=bark2", wantCode: http.StatusOK, wantItems: 1},
+		{name: "other state", query: "userId=user1&state=snoozed", wantCode: http.StatusOK},
+		{name: "missing user", query: "pageSize=2", wantCode: http.StatusBadRequest},
+		{name: "invalid state", query: "userId=user1&state=deleted", wantCode: http.StatusBadRequest},
+		{name: "invalid page size", query: "userId=user1&pageSize=1000", wantCode: http.StatusBadRequest},
+		{name: "invalid page token", query: "userId=user1&pageToken=unknown", wantCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(service, http.MethodGet, "/inbox/?"+test.query, "")
+			if w.Code != test.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantCode, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var response dog.ListInboxResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Items) != test.wantItems || response.NextPageToken != test.wantPageToken {
+				t.Errorf("listed %d items with next page token %q, want %d and %q",
+					len(response.Items), response.NextPageToken, test.wantItems, test.wantPageToken)
+			}
+		})
+	}
+}
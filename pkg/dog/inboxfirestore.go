@@ -0,0 +1,117 @@
+package dog
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InboxFirestore is a Google Cloud Firestore-based data backend for inbox items.
+//
+// Listing a user's inbox requires a composite index on the inbox collection of userId ascending,
+// state ascending and receivedTime descending.
+type InboxFirestore struct {
+	FirestoreClient *firestore.Client
+}
+
+// Get returns an inbox item by ID.
+func (store *InboxFirestore) Get(ctx context.Context, ID string) (*InboxItem, error) {
+	// get document from datastore
+	docID := "inbox/" + ID
+	itemDoc, err := store.FirestoreClient.Doc(docID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert to item
+	var item InboxItem
+	err = itemDoc.DataTo(&item)
+	return &item, err
+}
+
+// Set writes an inbox item, replacing any existing item with the same key.
+func (store *InboxFirestore) Set(ctx context.Context, item *InboxItem) error {
+	docID := "inbox/" + item.ID
+	_, err := store.FirestoreClient.Doc(docID).Set(ctx, item)
+	return err
+}
+
+// Modify transactionally applies modify to an inbox item and writes the modified item, which is
+// returned. An error returned by modify aborts the transaction and is returned as is.
+func (store *InboxFirestore) Modify(ctx context.Context, ID string,
+	modify func(item *InboxItem) error) (*InboxItem, error) {
+
+	docRef := store.FirestoreClient.Doc("inbox/" + ID)
+
+	var item *InboxItem
+	err := store.FirestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		itemDoc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		item = new(InboxItem)
+		if err = itemDoc.DataTo(item); err != nil {
+			return err
+		}
+
+		if err = modify(item); err != nil {
+			return err
+		}
+		return tx.Set(docRef, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ListByUser returns a page of a user's inbox items in a given state, most recently received
+// first. The page token is the ID of the last item of the previous page.
+func (store *InboxFirestore) ListByUser(ctx context.Context, userID, state string, pageSize int,
+	pageToken string) ([]*InboxItem, string, error) {
+
+	query := store.FirestoreClient.Collection("inbox").
+		Where("userId", "==", userID).
+		Where("state", "==", state).
+		OrderBy("receivedTime", firestore.Desc).
+		Limit(pageSize)
+
+	// resume after last item of previous page
+	if pageToken != "" {
+		lastDoc, err := store.FirestoreClient.Doc("inbox/" + pageToken).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, "", ErrInvalidPageToken
+		} else if err != nil {
+			return nil, "", err
+		}
+		query = query.StartAfter(lastDoc)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	items := []*InboxItem{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		var item InboxItem
+		if err = doc.DataTo(&item); err != nil {
+			return nil, "", err
+		}
+		items = append(items, &item)
+	}
+
+	var nextPageToken string
+	if len(items) == pageSize {
+		nextPageToken = items[len(items)-1].ID
+	}
+	return items, nextPageToken, nil
+}
@@ -0,0 +1,128 @@
+package dog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinInterval is the shortest interval allowed for an IntervalSchedule.
+const MinInterval = time.Minute
+
+// maxDuration is the longest time.Duration, to which time.Time.Sub saturates.
+const maxDuration = time.Duration(math.MaxInt64)
+
+// IntervalSchedule is a schedule that fires at a fixed interval from an anchor time.
+type IntervalSchedule struct {
+	Every  time.Duration
+	Anchor time.Time
+}
+
+func init() {
+	RegisterScheduleType("interval", func(b json.RawMessage) (Schedule, error) {
+		var schedule IntervalSchedule
+		err := json.Unmarshal(b, &schedule)
+		return schedule, err
+	})
+}
+
+// intervalScheduleJSON is the JSON form of an IntervalSchedule.
+type intervalScheduleJSON struct {
+	Every  string     `json:"every"`
+	Anchor *time.Time `json:"anchor,omitempty"`
+}
+
+// Next implements the Schedule interface.
+func (s IntervalSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.Anchor) {
+		return s.Anchor
+	}
+
+	// move a distant anchor toward t in whole intervals until the time between them is a Duration
+	anchor := s.Anchor
+	for t.Sub(anchor) == maxDuration {
+		anchor = anchor.Add(maxDuration / s.Every * s.Every)
+	}
+	intervals := t.Sub(anchor)/s.Every + 1
+	return anchor.Add(intervals * s.Every)
+}
+
+// UnmarshalJSON parses a JSON object with an "every" interval, such as "90m" or "10d", and an
+// optional RFC 3339 "anchor" time. The anchor defaults to the current minute.
+func (s *IntervalSchedule) UnmarshalJSON(b []byte) error {
+	var raw intervalScheduleJSON
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	s.Every, err = parseInterval(raw.Every)
+	if err != nil {
+		return err
+	}
+	if s.Every < MinInterval {
+		return fmt.Errorf("interval must be at least %s", MinInterval)
+	}
+
+	if raw.Anchor != nil {
+		s.Anchor = *raw.Anchor
+	} else {
+		s.Anchor = time.Now().Truncate(time.Minute)
+	}
+	return nil
+}
+
+// MarshalJSON writes the interval and anchor as a JSON object.
+func (s IntervalSchedule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&intervalScheduleJSON{
+		Every:  formatInterval(s.Every),
+		Anchor: &s.Anchor,
+	})
+}
+
+// parseInterval parses a duration that may begin with a number of days, such as "10d" or "1d12h".
+// Intervals must not be negative, nor have negative parts.
+func parseInterval(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("missing interval")
+	}
+	if strings.Contains(s, "-") {
+		return 0, fmt.Errorf("interval must not be negative: %s", s)
+	}
+
+	var days time.Duration
+	if i := strings.Index(s, "d"); i >= 0 {
+		n, err := strconv.Atoi(s[:i])
+		if err != nil || time.Duration(n) > maxDuration/(24*time.Hour) {
+			return 0, fmt.Errorf("invalid interval: %s", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		s = s[i+1:]
+		if s == "" {
+			return days, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || days+d < days {
+		return 0, fmt.Errorf("invalid interval: %s", s)
+	}
+	return days + d, nil
+}
+
+// formatInterval formats a duration as parsed by parseInterval.
+func formatInterval(d time.Duration) string {
+	day := 24 * time.Hour
+	if d < day {
+		return d.String()
+	}
+	s := fmt.Sprintf("%dd", d/day)
+	if d%day != 0 {
+		s += (d % day).String()
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package dog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	valid := map[string]time.Duration{
+		"90m":    90 * time.Minute,
+		"10d":    240 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"0d1m":   time.Minute,
+		"1h0.5s": time.Hour + 500*time.Millisecond,
+	}
+	for s, want := range valid {
+		got, err := parseInterval(s)
+		if err != nil || got != want {
+			t.Errorf("parseInterval(%q) = %s, %v, want %s", s, got, err, want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"soon",
+		"d",
+		"1w",
+		"-1h",
+		"1d-23h",
+		"-1d25h",
+		"200000d",
+		"106751d24h",
+	}
+	for _, s := range invalid {
+		if got, err := parseInterval(s); err == nil {
+			t.Errorf("parseInterval(%q) = %s, want error", s, got)
+		}
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	for _, d := range []time.Duration{time.Minute, 90 * time.Minute, 48 * time.Hour, 36*time.Hour + time.Second} {
+		got, err := parseInterval(formatInterval(d))
+		if err != nil || got != d {
+			t.Errorf("parseInterval(formatInterval(%s)) = %s, %v", d, got, err)
+		}
+	}
+}
+
+func TestIntervalScheduleNext(t *testing.T) {
+	anchor := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	s := IntervalSchedule{Every: 90 * time.Minute, Anchor: anchor}
+
+	tests := []struct {
+		after, want time.Time
+	}{
+		{after: anchor.Add(-time.Hour), want: anchor},
+		{after: anchor, want: anchor.Add(90 * time.Minute)},
+		{after: anchor.Add(time.Hour), want: anchor.Add(90 * time.Minute)},
+		{after: anchor.Add(90 * time.Minute), want: anchor.Add(180 * time.Minute)},
+		{after: anchor.AddDate(1, 0, 0), want: time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		if got := s.Next(test.after); !got.Equal(test.want) {
+			t.Errorf("Next(%s) = %s, want %s", test.after, got, test.want)
+		}
+	}
+}
+
+func TestIntervalScheduleNextDistantAnchor(t *testing.T) {
+	// the time since the anchor is longer than a Duration
+	s := IntervalSchedule{Every: 7 * 24 * time.Hour}
+	now := time.Date(2024, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	got := s.Next(now)
+	if !got.After(now) || got.Sub(now) > s.Every {
+		t.Fatalf("Next(%s) = %s, want within one interval after", now, got)
+	}
+	// 0001-01-01 and 2024-06-10 are both Mondays
+	if want := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", now, got, want)
+	}
+}
+
+func TestIntervalScheduleJSON(t *testing.T) {
+	var s IntervalSchedule
+	if err := json.Unmarshal([]byte(`{"every": "1d12h", "anchor": "2024-01-01T09:00:00Z"}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"every":"1d12h0m0s","anchor":"2024-01-01T09:00:00Z"}`; string(b) != want {
+		t.Errorf("Marshal() = %s, want %s", b, want)
+	}
+
+	for _, raw := range []string{`{"every": "30s"}`, `{"every": "1d-23h"}`, `{}`, `"1h"`} {
+		if err := json.Unmarshal([]byte(raw), &s); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", raw)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package dog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NaturalSchedule is a schedule described in English, such as "every weekday at 9am", "twice a
+// day" or "first Monday of the month at noon". The description is compiled to a schedule of
+// another type, which is kept alongside it so that the schedule does not change if the description
+// would compile differently later.
+//
+// Descriptions may end with a time zone, as in "every day at 8am in America/Chicago". Barks
+// without a time of day are at 9am.
+type NaturalSchedule struct {
+	Text         string
+	ScheduleType string
+	Schedule
+}
+
+func init() {
+	RegisterScheduleType("natural", func(b json.RawMessage) (Schedule, error) {
+		var schedule NaturalSchedule
+		err := json.Unmarshal(b, &schedule)
+		return schedule, err
+	})
+}
+
+// naturalScheduleJSON is the JSON object form of a NaturalSchedule.
+type naturalScheduleJSON struct {
+	Text         string          `json:"text"`
+	ScheduleType string          `json:"scheduleType,omitempty"`
+	Schedule     json.RawMessage `json:"schedule,omitempty"`
+}
+
+// defaultTimeOfDay is the time of day of natural schedules without one.
+const defaultTimeOfDay = 9 * time.Hour
+
+// maxTimesPerDay is the most times per day of a "times a day" natural schedule.
+const maxTimesPerDay = 13
+
+var (
+	naturalTimeZone = regexp.MustCompile(`(?i)\s+in\s+([a-z_]+(?:/[a-z0-9_+-]+)*)$`)
+	naturalTime     = regexp.MustCompile(`\s+at\s+(.+)$`)
+	naturalClock    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?\s*(am|pm)?$`)
+	naturalTimesDay = regexp.MustCompile(`^(once|twice|thrice|\w+ times) (?:a|per|each) day$`)
+	naturalEvery    = regexp.MustCompile(`^every (\w+ )?(minute|hour|day|week)s?$`)
+	naturalWeekdays = regexp.MustCompile(`^(?:every|on) (other )?([a-z, ]+)$`)
+	naturalMonthly  = regexp.MustCompile(
+		`^(?:on )?(?:the )?(first|second|third|fourth|last) ([a-z]+) of (?:the|each|every) month$`)
+	naturalDate = regexp.MustCompile(`^on (\d{4}-\d{2}-\d{2})$`)
+)
+
+var naturalNumbers = map[string]int{
+	"once": 1, "twice": 2, "thrice": 3,
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7,
+	"eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13,
+	"other": 2,
+}
+
+var naturalOrdinals = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "last": -1,
+}
+
+var dayNames = map[string]string{
+	"sun": "sunday",
+	"mon": "monday",
+	"tue": "tuesday",
+	"wed": "wednesday",
+	"thu": "thursday",
+	"fri": "friday",
+	"sat": "saturday",
+}
+
+var rruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// UnmarshalJSON parses a JSON string description, or a JSON object with a "text" description and
+// optionally its compiled "scheduleType" and "schedule", into a NaturalSchedule.
+func (s *NaturalSchedule) UnmarshalJSON(b []byte) error {
+	var raw naturalScheduleJSON
+	err := json.Unmarshal(b, &raw.Text)
+	if err != nil {
+		if err = json.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+	}
+
+	s.Text = raw.Text
+	if raw.ScheduleType == "" {
+		s.ScheduleType, raw.Schedule, err = compileNatural(raw.Text, time.Now())
+		if err != nil {
+			return err
+		}
+	} else if raw.ScheduleType == "natural" {
+		return errors.New("natural schedule cannot compile to a natural schedule")
+	} else {
+		s.ScheduleType = raw.ScheduleType
+	}
+
+	s.Schedule, err = ParseSchedule(s.ScheduleType, raw.Schedule)
+	return err
+}
+
+// MarshalJSON writes the description and its compiled schedule as a JSON object.
+func (s NaturalSchedule) MarshalJSON() ([]byte, error) {
+	schedule, err := json.Marshal(s.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&naturalScheduleJSON{
+		Text:         s.Text,
+		ScheduleType: s.ScheduleType,
+		Schedule:     schedule,
+	})
+}
+
+// compileNatural compiles a description into the raw JSON of a schedule of another type. Relative
+// parts of the description, such as the start of intervals, are relative to now.
+func compileNatural(text string, now time.Time) (string, json.RawMessage, error) {
+	phrase := strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimSpace(text), ".")), " ")
+	if phrase == "" {
+		return "", nil, errors.New("missing schedule description")
+	}
+
+	// time zone keeps its case, the rest of the description does not
+	timeZone := ""
+	if match := naturalTimeZone.FindStringSubmatch(phrase); match != nil {
+		timeZone = match[1]
+		if strings.EqualFold(timeZone, "utc") {
+			timeZone = "UTC"
+		}
+		phrase = phrase[:len(phrase)-len(match[0])]
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid time zone: %s", timeZone)
+	}
+	phrase = strings.ToLower(phrase)
+
+	timeOfDay := defaultTimeOfDay
+	hasTime := false
+	if match := naturalTime.FindStringSubmatch(phrase); match != nil {
+		timeOfDay, err = parseClock(match[1])
+		if err != nil {
+			return "", nil, err
+		}
+		hasTime = true
+		phrase = phrase[:len(phrase)-len(match[0])]
+	}
+	hour, minute := int(timeOfDay/time.Hour), int(timeOfDay%time.Hour/time.Minute)
+
+	switch phrase {
+	case "every day", "daily", "each day":
+		return compiledCron(fmt.Sprintf("%d %d * * *", minute, hour), timeZone)
+	case "every weekday", "weekdays", "on weekdays":
+		return compiledCron(fmt.Sprintf("%d %d * * 1-5", minute, hour), timeZone)
+	case "every weekend", "weekends", "on weekends", "every weekend day":
+		return compiledCron(fmt.Sprintf("%d %d * * 0,6", minute, hour), timeZone)
+	case "every hour", "hourly":
+		if hasTime {
+			return "", nil, errors.New("hourly schedules cannot have a time of day")
+		}
+		return compiledCron("0 * * * *", timeZone)
+	}
+
+	if match := naturalTimesDay.FindStringSubmatch(phrase); match != nil {
+		n, ok := naturalNumber(strings.TrimSuffix(match[1], " times"))
+		if !ok || n < 1 || n > maxTimesPerDay {
+			return "", nil, fmt.Errorf("times a day must be between 1 and %d", maxTimesPerDay)
+		}
+		if hasTime {
+			return "", nil, errors.New("times a day cannot have a time of day")
+		}
+		return compiledCron(fmt.Sprintf("0 %s * * *", spreadHours(n)), timeZone)
+	}
+
+	if match := naturalEvery.FindStringSubmatch(phrase); match != nil {
+		n := 1
+		if match[1] != "" {
+			var ok bool
+			if n, ok = naturalNumber(strings.TrimSpace(match[1])); !ok || n < 1 {
+				return "", nil, fmt.Errorf("invalid schedule: %s", text)
+			}
+		}
+		unit := map[string]time.Duration{
+			"minute": time.Minute,
+			"hour":   time.Hour,
+			"day":    24 * time.Hour,
+			"week":   7 * 24 * time.Hour,
+		}[match[2]]
+
+		// days and weeks keep their time of day across daylight saving transitions, starting
+		// on the first day on which that time is still to come
+		if unit >= 24*time.Hour {
+			start := now
+			if atTimeOfDay(now.In(loc), timeOfDay).Before(now) {
+				start = now.In(loc).AddDate(0, 0, 1)
+			}
+			freq := "DAILY"
+			if unit > 24*time.Hour {
+				freq = "WEEKLY"
+			}
+			return compiledRRule(fmt.Sprintf("FREQ=%s;INTERVAL=%d", freq, n), hour, minute, loc, start)
+		}
+		if hasTime {
+			return "", nil, errors.New("intervals shorter than a day cannot have a time of day")
+		}
+		anchor := now.In(loc).Truncate(time.Minute)
+		return compiled("interval", &intervalScheduleJSON{
+			Every:  formatInterval(time.Duration(n) * unit),
+			Anchor: &anchor,
+		})
+	}
+
+	if match := naturalMonthly.FindStringSubmatch(phrase); match != nil {
+		weekday, ok := weekdays[dayAbbreviation(match[2])]
+		if !ok {
+			return "", nil, fmt.Errorf("invalid day: %s", match[2])
+		}
+		rule := fmt.Sprintf("FREQ=MONTHLY;BYDAY=%+d%s",
+			naturalOrdinals[match[1]], rruleWeekdays[weekday])
+		return compiledRRule(rule, hour, minute, loc, now)
+	}
+
+	if match := naturalWeekdays.FindStringSubmatch(phrase); match != nil {
+		days, err := parseWeekdays(match[2])
+		if err != nil {
+			return "", nil, err
+		}
+		if match[1] != "" {
+			byDay := make([]string, len(days))
+			for i, day := range days {
+				byDay[i] = rruleWeekdays[day]
+			}
+			rule := fmt.Sprintf("FREQ=WEEKLY;INTERVAL=2;BYDAY=%s", strings.Join(byDay, ","))
+			return compiledRRule(rule, hour, minute, loc, now)
+		}
+		dow := make([]string, len(days))
+		for i, day := range days {
+			dow[i] = strconv.Itoa(int(day))
+		}
+		return compiledCron(fmt.Sprintf("%d %d * * %s", minute, hour, strings.Join(dow, ",")), timeZone)
+	}
+
+	if match := naturalDate.FindStringSubmatch(phrase); match != nil {
+		date, err := time.ParseInLocation("2006-01-02", match[1], loc)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid date: %s", match[1])
+		}
+		return compiled("at", atTimeOfDay(date, timeOfDay))
+	}
+
+	return "", nil, fmt.Errorf("schedule not understood: %s", text)
+}
+
+// compiled returns a schedule type with the JSON of its schedule.
+func compiled(scheduleType string, schedule interface{}) (string, json.RawMessage, error) {
+	b, err := json.Marshal(schedule)
+	return scheduleType, b, err
+}
+
+// compiledCron returns a cron schedule of a spec in a time zone.
+func compiledCron(spec, timeZone string) (string, json.RawMessage, error) {
+	return compiled("cron", &cronScheduleJSON{
+		Spec:     spec,
+		TimeZone: timeZone,
+	})
+}
+
+// compiledRRule returns an rrule schedule of a recurrence rule at a time of day, starting today.
+func compiledRRule(rule string, hour, minute int, loc *time.Location,
+	now time.Time) (string, json.RawMessage, error) {
+
+	dtstart := now.In(loc).Format("20060102")
+	if loc == time.UTC {
+		dtstart = fmt.Sprintf("DTSTART:%sT000000Z", dtstart)
+	} else {
+		dtstart = fmt.Sprintf("DTSTART;TZID=%s:%sT000000", loc, dtstart)
+	}
+	rule = fmt.Sprintf("RRULE:%s;BYHOUR=%d;BYMINUTE=%d;BYSECOND=0", rule, hour, minute)
+	return compiled("rrule", dtstart+"\n"+rule)
+}
+
+// parseClock parses a time of day such as "9am", "9:30 pm", "18:00", "noon" or "midnight".
+func parseClock(s string) (time.Duration, error) {
+	switch s {
+	case "noon":
+		return 12 * time.Hour, nil
+	case "midnight":
+		return 0, nil
+	}
+
+	match := naturalClock.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("invalid time of day: %s", s)
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+
+	if match[3] != "" {
+		if hour < 1 || hour > 12 {
+			return 0, fmt.Errorf("invalid time of day: %s", s)
+		}
+		hour %= 12
+		if match[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, fmt.Errorf("invalid time of day: %s", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// parseWeekdays parses a list of days such as "monday, wednesday and friday".
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if field == "and" {
+			continue
+		}
+		weekday, ok := weekdays[dayAbbreviation(field)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day: %s", field)
+		}
+		days = append(days, weekday)
+	}
+	if len(days) == 0 {
+		return nil, errors.New("missing days")
+	}
+	return days, nil
+}
+
+// dayAbbreviation returns the three-letter abbreviation of a day name such as "mon", "tues" or
+// "mondays", or an empty string if it is not a day name.
+func dayAbbreviation(day string) string {
+	day = strings.TrimSuffix(day, "s")
+	if len(day) < 3 {
+		return ""
+	}
+	abbreviation := day[:3]
+	if !strings.HasPrefix(dayNames[abbreviation], day) {
+		return ""
+	}
+	return abbreviation
+}
+
+// naturalNumber parses a number written in digits or as a word.
+func naturalNumber(s string) (int, bool) {
+	if n, ok := naturalNumbers[s]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// spreadHours returns a cron hour field of n hours spread from 9am to 9pm.
+func spreadHours(n int) string {
+	if n == 1 {
+		return strconv.Itoa(int(defaultTimeOfDay / time.Hour))
+	}
+	step := 12 / (n - 1)
+	hours := make([]string, n)
+	for i := range hours {
+		hours[i] = strconv.Itoa(int(defaultTimeOfDay/time.Hour) + i*step)
+	}
+	return strings.Join(hours, ",")
+}
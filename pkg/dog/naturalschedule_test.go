@@ -0,0 +1,132 @@
+package dog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCompileNatural(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text     string
+		wantType string
+		wantNext time.Time
+	}{
+		{"every day", "cron", time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC)},
+		{"every weekday at 9am", "cron", time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC)},
+		{"Every day at 8AM in Asia/Tokyo.", "cron", time.Date(2024, 1, 3, 23, 0, 0, 0, time.UTC)},
+		{"on mondays and fridays at 7am in America/Chicago", "cron", time.Date(2024, 1, 5, 13, 0, 0, 0, time.UTC)},
+		{"weekends at noon", "cron", time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC)},
+		{"hourly", "cron", time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC)},
+		{"twice a day", "cron", time.Date(2024, 1, 3, 21, 0, 0, 0, time.UTC)},
+		{"every 2 hours", "interval", time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{"every three days at 8:30 pm", "rrule", time.Date(2024, 1, 3, 20, 30, 0, 0, time.UTC)},
+		{"every 2 days at 9am", "rrule", time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC)},
+		{"every week", "rrule", time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC)},
+		{"first Monday of the month at noon", "rrule", time.Date(2024, 2, 5, 12, 0, 0, 0, time.UTC)},
+		{"the last friday of each month", "rrule", time.Date(2024, 1, 26, 9, 0, 0, 0, time.UTC)},
+		{"every other tuesday and thursday", "rrule", time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC)},
+		{"on 2024-02-29 at midnight", "at", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		scheduleType, raw, err := compileNatural(test.text, now)
+		if err != nil {
+			t.Errorf("compileNatural(%q) = %v", test.text, err)
+			continue
+		}
+		if scheduleType != test.wantType {
+			t.Errorf("compileNatural(%q) type = %s, want %s", test.text, scheduleType, test.wantType)
+			continue
+		}
+		schedule, err := ParseSchedule(scheduleType, raw)
+		if err != nil {
+			t.Errorf("compileNatural(%q) = %s, which does not parse: %v", test.text, raw, err)
+			continue
+		}
+		if next := schedule.Next(now); !next.Equal(test.wantNext) {
+			t.Errorf("compileNatural(%q) = %s, next %s, want %s", test.text, raw, next, test.wantNext)
+		}
+	}
+}
+
+func TestCompileNaturalDaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// every time keeps the time of day through the end of daylight saving time on November 3
+	for _, text := range []string{
+		"every 2 days at 9am in America/New_York",
+		"every day at 9am in America/New_York",
+		"every week at 9am in America/New_York",
+	} {
+		scheduleType, raw, err := compileNatural(text, time.Date(2024, 10, 31, 12, 0, 0, 0, ny))
+		if err != nil {
+			t.Fatalf("compileNatural(%q) = %v", text, err)
+		}
+		schedule, err := ParseSchedule(scheduleType, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := time.Date(2024, 10, 31, 12, 0, 0, 0, ny)
+		for i := 0; i < 5; i++ {
+			next = schedule.Next(next)
+			if local := next.In(ny); local.Hour() != 9 || local.Minute() != 0 {
+				t.Errorf("compileNatural(%q) = %s, barks at %s", text, raw, local)
+			}
+		}
+		if next.Before(time.Date(2024, 11, 4, 0, 0, 0, 0, ny)) {
+			t.Errorf("compileNatural(%q): checked times end at %s, before the transition", text, next)
+		}
+	}
+}
+
+func TestCompileNaturalErrors(t *testing.T) {
+	now := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+	for _, text := range []string{
+		"",
+		"whenever",
+		"every funday",
+		"every day at 25:00",
+		"every day at 13pm",
+		"every day in Mars/Base",
+		"every hour at 9am",
+		"every 5 minutes at noon",
+		"twice a day at noon",
+		"fourteen times a day",
+		"on 2024-02-30",
+	} {
+		if scheduleType, raw, err := compileNatural(text, now); err == nil {
+			t.Errorf("compileNatural(%q) = %s %s, want error", text, scheduleType, raw)
+		}
+	}
+}
+
+func TestNaturalScheduleJSON(t *testing.T) {
+	// a compiled schedule is kept even if the description would compile differently
+	raw := `{"text": "every day", "scheduleType": "cron", "schedule": "0 7 * * *"}`
+	var s NaturalSchedule
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+	if want := time.Date(2024, 1, 4, 7, 0, 0, 0, time.UTC); !s.Next(now).Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", now, s.Next(now), want)
+	}
+	b, err := json.Marshal(s)
+	if want := `{"text":"every day","scheduleType":"cron","schedule":"0 7 * * *"}`; err != nil || string(b) != want {
+		t.Errorf("Marshal() = %s, %v, want %s", b, err, want)
+	}
+
+	if err := json.Unmarshal([]byte(`"every weekday"`), &s); err != nil || s.ScheduleType != "cron" {
+		t.Errorf("Unmarshal(description) = %v, type %s", err, s.ScheduleType)
+	}
+	if err := json.Unmarshal([]byte(`{"text": "x", "scheduleType": "natural", "schedule": "x"}`), &s); err == nil {
+		t.Error("Unmarshal() of a natural schedule compiled to itself succeeded, want error")
+	}
+}
@@ -0,0 +1,157 @@
+package dog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	defaultPreviewCount = 5
+	maxPreviewCount     = 50
+)
+
+var maxPreviewRequestSizeBytes int64 = 20000
+
+// PreviewScheduleRequest is the request type for previewing a schedule. The fields are as in
+// CreateDogRequest, with UserID used for the user's quiet hours.
+type PreviewScheduleRequest struct {
+	UserID       string          `json:"userId,omitempty"`
+	ScheduleType string          `json:"scheduleType"`
+	Schedule     json.RawMessage `json:"schedule"`
+	QuietHours   QuietHours      `json:"quietHours,omitempty"`
+	Bounds       *Bounds         `json:"bounds,omitempty"`
+	CalendarID   string          `json:"calendarId,omitempty"`
+	Count        int             `json:"count,omitempty"`
+}
+
+// UpcomingResponse is the response type for the upcoming times of a schedule.
+type UpcomingResponse struct {
+	ScheduleType string          `json:"scheduleType"`
+	Schedule     json.RawMessage `json:"schedule"`
+	Times        []time.Time     `json:"times"`
+}
+
+// PreviewSchedule is a handler for listing the next times of a schedule without creating a dog.
+// Times honor the schedule's time zone, bounds, calendar and quiet hours.
+func (service *Service) PreviewSchedule(w http.ResponseWriter, r *http.Request) {
+	var requestBody PreviewScheduleRequest
+
+	// read request body into struct
+	r.Body = http.MaxBytesReader(w, r.Body, maxPreviewRequestSizeBytes)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		service.Logf(r, `result=DecodeError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	count := requestBody.Count
+	if count == 0 {
+		count = defaultPreviewCount
+	} else if count < 1 || count > maxPreviewCount {
+		service.Logf(r, `result=InvalidCountError`)
+		bark.RespondError(w, http.StatusBadRequest,
+			fmt.Sprintf("count must be between 1 and %d", maxPreviewCount))
+		return
+	}
+
+	scheduleRaw, ok := service.parseSchedule(w, r, requestBody.ScheduleType, requestBody.Schedule,
+		requestBody.Bounds, requestBody.QuietHours)
+	if !ok {
+		return
+	}
+
+	// preview as a dog created now
+	dog := &Dog{
+		CreationTime: time.Now(),
+		UserID:       requestBody.UserID,
+		ScheduleType: requestBody.ScheduleType,
+		ScheduleRaw:  scheduleRaw,
+		QuietHours:   requestBody.QuietHours,
+		Bounds:       requestBody.Bounds,
+		CalendarID:   requestBody.CalendarID,
+	}
+	service.respondUpcoming(w, r, dog, count)
+}
+
+// GetUpcoming is a handler for listing the next times that a dog will bark. The number of times
+// is set by the count query parameter.
+func (service *Service) GetUpcoming(w http.ResponseWriter, r *http.Request) {
+	dogID := chi.URLParam(r, "dogID")
+
+	count := defaultPreviewCount
+	if countParam := r.URL.Query().Get("count"); countParam != "" {
+		var err error
+		count, err = strconv.Atoi(countParam)
+		if err != nil || count < 1 || count > maxPreviewCount {
+			service.Logf(r, `result=InvalidCountError`)
+			bark.RespondError(w, http.StatusBadRequest,
+				fmt.Sprintf("count must be between 1 and %d", maxPreviewCount))
+			return
+		}
+	}
+
+	// get dog from datastore
+	dog, err := service.DogGetter.Get(r.Context(), dogID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetDog dogID=%s result=OK`, dogID)
+	case codes.NotFound:
+		service.Logf(r, `action=GetDog dogID=%s result=NotFoundError`, dogID)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("dog not found with ID: ", dogID))
+		return
+	default:
+		service.Logf(r, `action=GetDog dogID=%s result=InternalError errorText="%s"`,
+			dogID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	service.respondUpcoming(w, r, dog, count)
+}
+
+// respondUpcoming responds with up to count upcoming times of a dog. The first time is the dog's
+// pending task, if it has one.
+func (service *Service) respondUpcoming(w http.ResponseWriter, r *http.Request, dog *Dog, count int) {
+	times := []time.Time{}
+	if !dog.Completed {
+		schedule, err := service.TasksClient.Schedule(r.Context(), dog)
+		if err != nil {
+			service.Logf(r, `action=GetSchedule dogID=%s result=InternalError errorText="%s"`,
+				dog.ID, err)
+			bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+			return
+		}
+
+		// bounded schedules limit times by the barks already made, not by upcoming ones
+		if dog.Bounds != nil && dog.Bounds.MaxBarks > 0 && dog.Bounds.MaxBarks-dog.BarkCount < count {
+			count = dog.Bounds.MaxBarks - dog.BarkCount
+		}
+
+		t := time.Now()
+		if !dog.NextTaskTime.IsZero() && count > 0 {
+			t = dog.NextTaskTime
+			times = append(times, t)
+		}
+		for len(times) < count {
+			if t = schedule.Next(t); t.IsZero() {
+				break
+			}
+			times = append(times, t)
+		}
+	}
+	service.Logf(r, `action=ListUpcoming dogID=%s count=%d result=OK`, dog.ID, len(times))
+
+	bark.RespondSuccess(w, http.StatusOK, &UpcomingResponse{
+		ScheduleType: dog.ScheduleType,
+		Schedule:     dog.ScheduleRaw,
+		Times:        times,
+	})
+}
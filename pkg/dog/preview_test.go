@@ -0,0 +1,207 @@
+package dog_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/dog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dogMap is an in-memory DoggoGetter.
+type dogMap map[string]*dog.Dog
+
+func (dogs dogMap) Get(ctx context.Context, ID string) (*dog.Dog, error) {
+	d, found := dogs[ID]
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "dog not found with ID: %s", ID)
+	}
+	return d, nil
+}
+
+// upcomingTimes decodes the times of an upcoming response.
+func upcomingTimes(t *testing.T, body []byte) []time.Time {
+	var response dog.UpcomingResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Times
+}
+
+func TestPreviewSchedule(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endAt := time.Now().Add(72 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		// wantTimes is the number of times previewed, or -1 if checked by check
+		wantTimes int
+		check     func(t *testing.T, times []time.Time)
+	}{
+		{
+			name:      "default count",
+			body:      `{"scheduleType": "cron", "schedule": "0 9 * * *"}`,
+			wantCode:  http.StatusOK,
+			wantTimes: 5,
+		},
+		{
+			name:      "time zone",
+			body:      `{"scheduleType": "cron", "schedule": {"spec": "0 9 * * *", "timeZone": "America/New_York"}, "count": 3}`,
+			wantCode:  http.StatusOK,
+			wantTimes: 3,
+			check: func(t *testing.T, times []time.Time) {
+				for _, next := range times {
+					if local := next.In(newYork); local.Hour() != 9 || local.Minute() != 0 {
+						t.Errorf("time %s is %s in New York, want 9:00", next, local.Format("15:04"))
+					}
+				}
+			},
+		},
+		{
+			name: "quiet hours",
+			body: `{"scheduleType": "cron", "schedule": "0 * * * *", "count": 24,
+				"quietHours": [{"start": "22:00", "end": "07:00", "timeZone": "UTC"}]}`,
+			wantCode:  http.StatusOK,
+			wantTimes: 24,
+			check: func(t *testing.T, times []time.Time) {
+				for _, next := range times {
+					if hour := next.UTC().Hour(); hour >= 22 || hour < 7 {
+						t.Errorf("time %s is within quiet hours", next)
+					}
+				}
+			},
+		},
+		{
+			name:      "max barks",
+			body:      `{"scheduleType": "cron", "schedule": "0 9 * * *", "bounds": {"maxBarks": 2}}`,
+			wantCode:  http.StatusOK,
+			wantTimes: 2,
+		},
+		{
+			name:      "end time",
+			body:      `{"scheduleType": "cron", "schedule": "0 9 * * *", "bounds": {"endAt": "` + endAt + `"}}`,
+			wantCode:  http.StatusOK,
+			wantTimes: -1,
+			check: func(t *testing.T, times []time.Time) {
+				if len(times) < 2 || len(times) > 3 {
+					t.Errorf("previewed %d times, want the 2 or 3 before the end time", len(times))
+				}
+			},
+		},
+		{
+			name:     "invalid count",
+			body:     `{"scheduleType": "cron", "schedule": "0 9 * * *", "count": 51}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid schedule",
+			body:     `{"scheduleType": "cron", "schedule": "every day"}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &dog.Service{TasksClient: dog.Whisperer{}}
+			start := time.Now()
+			w := serve(service, http.MethodPost, "/schedules/preview", test.body)
+			if w.Code != test.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantCode, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			times := upcomingTimes(t, w.Body.Bytes())
+			if test.wantTimes >= 0 && len(times) != test.wantTimes {
+				t.Fatalf("previewed %d times, want %d", len(times), test.wantTimes)
+			}
+			for i, next := range times {
+				if next.Before(start) || (i > 0 && !next.After(times[i-1])) {
+					t.Errorf("times = %v, want increasing upcoming times", times)
+					break
+				}
+			}
+			if test.check != nil {
+				test.check(t, times)
+			}
+		})
+	}
+}
+
+func TestGetUpcoming(t *testing.T) {
+	next := time.Now().Truncate(10 * time.Minute).Add(time.Hour)
+	cronDog := func(ID string, bounds *dog.Bounds, barkCount int) *dog.Dog {
+		return &dog.Dog{
+			ID:           ID,
+			ScheduleType: "cron",
+			ScheduleRaw:  json.RawMessage(`"*/10 * * * *"`),
+			NextTaskName: "task-" + ID,
+			NextTaskTime: next,
+			Bounds:       bounds,
+			BarkCount:    barkCount,
+		}
+	}
+	completed := cronDog("completed", nil, 1)
+	completed.Completed = true
+	completed.NextTaskTime = time.Time{}
+
+	service := &dog.Service{
+		TasksClient: dog.Whisperer{},
+		DogGetter: dogMap{
+			"unbounded": cronDog("unbounded", nil, 0),
+			"oneleft":   cronDog("oneleft", &dog.Bounds{MaxBarks: 3}, 2),
+			"twoleft":   cronDog("twoleft", &dog.Bounds{MaxBarks: 5}, 3),
+			"completed": completed,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		target    string
+		wantCode  int
+		wantTimes int
+	}{
+		{name: "pending task first", target: "/dogs/unbounded/upcoming?count=4", wantCode: http.StatusOK, wantTimes: 4},
+		{name: "last bark", target: "/dogs/oneleft/upcoming", wantCode: http.StatusOK, wantTimes: 1},
+		{name: "barks left", target: "/dogs/twoleft/upcoming", wantCode: http.StatusOK, wantTimes: 2},
+		{name: "completed", target: "/dogs/completed/upcoming", wantCode: http.StatusOK, wantTimes: 0},
+		{name: "invalid count", target: "/dogs/unbounded/upcoming?count=0", wantCode: http.StatusBadRequest},
+		{name: "unknown dog", target: "/dogs/unknown/upcoming", wantCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(service, http.MethodGet, test.target, "")
+			if w.Code != test.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantCode, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			times := upcomingTimes(t, w.Body.Bytes())
+			if len(times) != test.wantTimes {
+				t.Fatalf("listed %d times, want %d", len(times), test.wantTimes)
+			}
+			if len(times) > 0 && !times[0].Equal(next) {
+				t.Errorf("first time = %s, want the pending task time %s", times[0], next)
+			}
+			for i := 1; i < len(times); i++ {
+				if times[i].Sub(times[i-1]) != 10*time.Minute {
+					t.Errorf("times = %v, want every 10 minutes after the pending task", times)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package dog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A QuietWindow is a recurring period during which barks are deferred to the end of the window.
+//
+// Start and End are times of day in "15:04" format. A window whose End is before its Start
+// extends into the following day, and a window whose Start equals its End lasts a full day.
+// Days restricts the window to the days on which it starts, as three-letter names such as "sat";
+// a window without days occurs daily. Times are evaluated in TimeZone, an IANA time zone name,
+// or UTC if empty.
+type QuietWindow struct {
+	Start    string   `json:"start" firestore:"start"`
+	End      string   `json:"end" firestore:"end"`
+	Days     []string `json:"days,omitempty" firestore:"days,omitempty"`
+	TimeZone string   `json:"timeZone,omitempty" firestore:"timeZone,omitempty"`
+}
+
+// QuietHours is a set of quiet windows.
+type QuietHours []QuietWindow
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Validate returns an error if any window is malformed, or if the windows together cover all times
+// so that barks could never be delivered.
+func (quiet QuietHours) Validate() error {
+	var windows quietWindows
+	for _, window := range quiet {
+		parsed, err := window.parse()
+		if err != nil {
+			return err
+		}
+		windows = append(windows, parsed)
+	}
+	if windows.always() {
+		return errors.New("quiet hours must not cover every day")
+	}
+	return nil
+}
+
+// Wrap returns a Schedule that defers the times of s to the end of quiet hours. The windows are
+// parsed once, and malformed windows are ignored.
+func (quiet QuietHours) Wrap(s Schedule) Schedule {
+	var windows quietWindows
+	for _, window := range quiet {
+		if parsed, err := window.parse(); err == nil {
+			windows = append(windows, parsed)
+		}
+	}
+	if len(windows) == 0 {
+		return s
+	}
+	return withDeferral(s, windows)
+}
+
+// quietWindows is a Deferral that defers times to the end of the quiet window containing them.
+type quietWindows []*parsedQuietWindow
+
+// Defer implements the Deferral interface.
+func (windows quietWindows) Defer(t time.Time) time.Time {
+	for _, window := range windows {
+		if end, ok := window.contains(t); ok {
+			return end
+		}
+	}
+	return t
+}
+
+// always reports whether the windows leave no time to bark within a week, or within as many
+// deferrals as a DeferredSchedule makes.
+func (windows quietWindows) always() bool {
+	if len(windows) == 0 {
+		return false
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t := start
+	for i := 0; i < maxDeferrals; i++ {
+		deferred := windows.Defer(t)
+		if deferred.Equal(t) {
+			return false
+		}
+		t = deferred
+		if t.Sub(start) > 8*24*time.Hour {
+			break
+		}
+	}
+	return true
+}
+
+// parsedQuietWindow is a QuietWindow with its fields parsed.
+type parsedQuietWindow struct {
+	start, end time.Duration
+	days       map[time.Weekday]bool
+	loc        *time.Location
+}
+
+// parse parses the fields of a quiet window.
+func (window QuietWindow) parse() (*parsedQuietWindow, error) {
+	start, err := parseTimeOfDay(window.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet window start: %s", window.Start)
+	}
+	end, err := parseTimeOfDay(window.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet window end: %s", window.End)
+	}
+	loc, err := time.LoadLocation(window.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet window time zone: %s", window.TimeZone)
+	}
+
+	var days map[time.Weekday]bool
+	if len(window.Days) > 0 {
+		days = make(map[time.Weekday]bool)
+		for _, day := range window.Days {
+			weekday, found := weekdays[strings.ToLower(day)]
+			if !found {
+				return nil, fmt.Errorf("invalid quiet window day: %s", day)
+			}
+			days[weekday] = true
+		}
+	}
+
+	return &parsedQuietWindow{
+		start: start,
+		end:   end,
+		days:  days,
+		loc:   loc,
+	}, nil
+}
+
+// contains reports whether t is within an occurrence of the window, and returns the end of that
+// occurrence.
+func (parsed *parsedQuietWindow) contains(t time.Time) (time.Time, bool) {
+	// check occurrences starting on the day of t and the day before
+	local := t.In(parsed.loc)
+	for _, dayOffset := range []int{0, -1} {
+		day := time.Date(local.Year(), local.Month(), local.Day()+dayOffset, 0, 0, 0, 0, parsed.loc)
+		if parsed.days != nil && !parsed.days[day.Weekday()] {
+			continue
+		}
+
+		start := atTimeOfDay(day, parsed.start)
+		endDay := day
+		if parsed.end <= parsed.start {
+			endDay = day.AddDate(0, 0, 1)
+		}
+		end := atTimeOfDay(endDay, parsed.end)
+
+		if !t.Before(start) && t.Before(end) {
+			return end, true
+		}
+	}
+	return t, false
+}
+
+// parseTimeOfDay parses a "15:04" time of day as a duration since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// atTimeOfDay returns the wall clock time of day on the date of day, in its location.
+func atTimeOfDay(day time.Time, timeOfDay time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		int(timeOfDay/time.Hour), int(timeOfDay%time.Hour/time.Minute), 0, 0, day.Location())
+}
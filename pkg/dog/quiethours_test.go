@@ -0,0 +1,161 @@
+package dog
+
+import (
+	"testing"
+	"time"
+)
+
+// everyMinute is a Schedule that fires at the start of every minute.
+type everyMinute struct{}
+
+func (everyMinute) Next(t time.Time) time.Time {
+	return t.Truncate(time.Minute).Add(time.Minute)
+}
+
+func TestQuietHoursWrap(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		name  string
+		quiet QuietHours
+		after string
+		want  string
+	}{
+		{
+			name:  "outside window",
+			quiet: QuietHours{{Start: "22:00", End: "07:00", TimeZone: "America/New_York"}},
+			after: "2024-03-06 12:00",
+			want:  "2024-03-06 12:01",
+		},
+		{
+			name:  "overnight window before midnight",
+			quiet: QuietHours{{Start: "22:00", End: "07:00", TimeZone: "America/New_York"}},
+			after: "2024-03-06 23:00",
+			want:  "2024-03-07 07:00",
+		},
+		{
+			name:  "overnight window after midnight",
+			quiet: QuietHours{{Start: "22:00", End: "07:00", TimeZone: "America/New_York"}},
+			after: "2024-03-07 03:00",
+			want:  "2024-03-07 07:00",
+		},
+		{
+			name:  "window on other days",
+			quiet: QuietHours{{Start: "09:00", End: "17:00", Days: []string{"sat", "sun"}, TimeZone: "America/New_York"}},
+			after: "2024-03-06 10:00",
+			want:  "2024-03-06 10:01",
+		},
+		{
+			name:  "full day window",
+			quiet: QuietHours{{Start: "00:00", End: "00:00", Days: []string{"Sun"}, TimeZone: "America/New_York"}},
+			after: "2024-03-10 12:00",
+			want:  "2024-03-11 00:00",
+		},
+		{
+			name: "adjacent windows",
+			quiet: QuietHours{
+				{Start: "12:00", End: "13:00", TimeZone: "America/New_York"},
+				{Start: "13:00", End: "14:00", TimeZone: "America/New_York"},
+			},
+			after: "2024-03-06 12:30",
+			want:  "2024-03-06 14:00",
+		},
+		{
+			name:  "window in another time zone",
+			quiet: QuietHours{{Start: "17:00", End: "18:00", TimeZone: "UTC"}},
+			after: "2024-03-06 12:00",
+			want:  "2024-03-06 13:00",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.quiet.Wrap(everyMinute{}).Next(at(test.after))
+			if want := at(test.want); !got.Equal(want) {
+				t.Errorf("Next() = %s, want %s", got.In(ny), want)
+			}
+		})
+	}
+}
+
+func TestQuietHoursWrapCombined(t *testing.T) {
+	// times deferred out of the user's window land in the dog's, and must be deferred again
+	dogQuiet := QuietHours{{Start: "07:00", End: "08:00"}}
+	userQuiet := QuietHours{{Start: "22:00", End: "07:00"}}
+	schedule := userQuiet.Wrap(dogQuiet.Wrap(everyMinute{}))
+
+	got := schedule.Next(time.Date(2024, 3, 6, 23, 0, 0, 0, time.UTC))
+	if want := time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() = %s, want %s", got, want)
+	}
+	if deferred, ok := schedule.(*DeferredSchedule); !ok || len(deferred.Deferrals) != 2 {
+		t.Errorf("Wrap() = %#v, want a single schedule deferred by both quiet hours", schedule)
+	}
+}
+
+func TestQuietHoursValidate(t *testing.T) {
+	invalid := []QuietWindow{
+		{Start: "25:00", End: "07:00"},
+		{Start: "22:00", End: "7pm"},
+		{Start: "22:00", End: "07:00", Days: []string{"someday"}},
+		{Start: "22:00", End: "07:00", TimeZone: "Mars/Olympus_Mons"},
+	}
+	for _, window := range invalid {
+		if err := (QuietHours{window}).Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", window)
+		}
+	}
+
+	always := []QuietHours{
+		{{Start: "00:00", End: "00:00"}},
+		{{Start: "09:00", End: "09:00", Days: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}},
+		{{Start: "00:00", End: "12:00"}, {Start: "12:00", End: "00:00"}},
+		{{Start: "22:00", End: "22:00", TimeZone: "Asia/Tokyo"}, {Start: "08:00", End: "08:00", TimeZone: "America/New_York"}},
+	}
+	for _, quiet := range always {
+		if err := quiet.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error for quiet hours covering every day", quiet)
+		}
+	}
+
+	valid := []QuietHours{
+		{{Start: "22:00", End: "07:00", Days: []string{"Fri", "sat"}, TimeZone: "Europe/Paris"}},
+		{{Start: "00:00", End: "00:00", Days: []string{"sat", "sun"}}},
+		{{Start: "00:00", End: "12:00"}, {Start: "12:01", End: "00:00"}},
+	}
+	for _, quiet := range valid {
+		if err := quiet.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v, want nil", quiet, err)
+		}
+	}
+}
+
+// creepingDeferral defers every time by a minute, so times can never be deferred out of it.
+type creepingDeferral struct{}
+
+func (creepingDeferral) Defer(t time.Time) time.Time {
+	return t.Add(time.Minute)
+}
+
+func TestDeferredScheduleExhausted(t *testing.T) {
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	for _, schedule := range []Schedule{
+		&DeferredSchedule{Schedule: everyMinute{}, Deferrals: []Deferral{creepingDeferral{}}},
+		// a full-day window accepted before quiet hours were validated as a whole
+		QuietHours{{Start: "00:00", End: "00:00"}}.Wrap(everyMinute{}),
+	} {
+		if got := schedule.Next(now); !got.IsZero() {
+			t.Errorf("Next(%s) = %s, want zero time", now, got)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package dog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// maxRandomTimes is the most times per period allowed for a RandomSchedule.
+const maxRandomTimes = 100
+
+// RandomSchedule is a schedule that fires a number of times per day or week at random moments
+// within a daily window, with a minimum spacing between the times of each period.
+//
+// Start and End bound the window in "15:04" format and default to the whole day. Times are
+// evaluated in TimeZone, an IANA time zone name, or UTC if empty. The times of each period are
+// derived from Seed, so the same schedule always produces the same times.
+//
+// MinSpacing only applies within a period: each period's times are drawn independently, so with a
+// window ending near the end of the day, the last time of one period may be closer than MinSpacing
+// to the first time of the next.
+type RandomSchedule struct {
+	Times      int    `json:"times"`
+	Per        string `json:"per"`
+	Start      string `json:"start,omitempty"`
+	End        string `json:"end,omitempty"`
+	MinSpacing string `json:"minSpacing,omitempty"`
+	TimeZone   string `json:"timeZone,omitempty"`
+	Seed       int64  `json:"seed"`
+
+	start, end, spacing time.Duration
+	days                int
+	loc                 *time.Location
+}
+
+func init() {
+	RegisterScheduleType("random", func(b json.RawMessage) (Schedule, error) {
+		var schedule RandomSchedule
+		err := json.Unmarshal(b, &schedule)
+		return schedule, err
+	})
+}
+
+// Next implements the Schedule interface.
+func (s RandomSchedule) Next(t time.Time) time.Time {
+	periodStart := s.periodStart(t)
+	// every period has times, so the next time is in this period or the one after
+	for i := 0; i < 2; i++ {
+		for _, next := range s.times(periodStart) {
+			if next.After(t) {
+				return next
+			}
+		}
+		periodStart = periodStart.AddDate(0, 0, s.days)
+	}
+	return time.Time{}
+}
+
+// UnmarshalJSON parses and validates a JSON object into a RandomSchedule. A seed is generated if
+// the object does not have one.
+func (s *RandomSchedule) UnmarshalJSON(b []byte) error {
+	type plain RandomSchedule
+	err := json.Unmarshal(b, (*plain)(s))
+	if err != nil {
+		return err
+	}
+
+	if s.Times < 1 || s.Times > maxRandomTimes {
+		return fmt.Errorf("times must be between 1 and %d", maxRandomTimes)
+	}
+
+	switch s.Per {
+	case "", "day":
+		s.Per = "day"
+		s.days = 1
+	case "week":
+		s.days = 7
+	default:
+		return fmt.Errorf("invalid period: %s", s.Per)
+	}
+
+	if s.Start != "" {
+		s.start, err = parseTimeOfDay(s.Start)
+		if err != nil {
+			return fmt.Errorf("invalid window start: %s", s.Start)
+		}
+	}
+	s.end = 24 * time.Hour
+	if s.End != "" {
+		s.end, err = parseTimeOfDay(s.End)
+		if err != nil {
+			return fmt.Errorf("invalid window end: %s", s.End)
+		}
+	}
+	if s.end <= s.start {
+		return errors.New("window end must be after window start")
+	}
+
+	if s.MinSpacing != "" {
+		s.spacing, err = time.ParseDuration(s.MinSpacing)
+		if err != nil || s.spacing < 0 {
+			return fmt.Errorf("invalid minimum spacing: %s", s.MinSpacing)
+		}
+	}
+	if time.Duration(s.days)*(s.end-s.start) <= time.Duration(s.Times-1)*s.spacing {
+		return errors.New("window is too short for times at minimum spacing")
+	}
+
+	s.loc, err = time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return fmt.Errorf("invalid time zone: %s", s.TimeZone)
+	}
+
+	for s.Seed == 0 {
+		s.Seed = rand.New(rand.NewSource(time.Now().UnixNano())).Int63()
+	}
+	return nil
+}
+
+// periodStart returns the start of the day or week containing t. Weeks start on Monday.
+func (s RandomSchedule) periodStart(t time.Time) time.Time {
+	day := atTimeOfDay(t.In(s.loc), 0)
+	if s.days == 7 {
+		day = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day
+}
+
+// times returns the sorted times of the period starting at periodStart.
+func (s RandomSchedule) times(periodStart time.Time) []time.Time {
+	// choose offsets into the period's combined window time, leaving room for spacing
+	window := s.end - s.start
+	span := time.Duration(s.days)*window - time.Duration(s.Times-1)*s.spacing
+	year, month, day := periodStart.Date()
+	periodKey := int64(year*10000+int(month)*100+day) * 6364136223846793005
+	rng := rand.New(rand.NewSource(s.Seed ^ periodKey))
+
+	offsets := make([]time.Duration, s.Times)
+	for i := range offsets {
+		offsets[i] = time.Duration(rng.Int63n(int64(span))).Truncate(time.Second)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	// spread offsets by spacing and map them onto the daily windows
+	times := make([]time.Time, s.Times)
+	for i, offset := range offsets {
+		offset += time.Duration(i) * s.spacing
+		date := periodStart.AddDate(0, 0, int(offset/window))
+		times[i] = atTimeOfDay(date, s.start).Add(offset % window)
+	}
+	return times
+}
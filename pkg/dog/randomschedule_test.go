@@ -0,0 +1,90 @@
+package dog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRandomScheduleNext(t *testing.T) {
+	tests := []struct {
+		raw     string
+		periods int // periods checked
+		days    int // days per period
+	}{
+		{raw: `{"times": 3, "start": "09:00", "end": "17:00", "minSpacing": "2h", "seed": 1}`, periods: 14, days: 1},
+		{raw: `{"times": 1, "seed": 2}`, periods: 14, days: 1},
+		{raw: `{"times": 5, "per": "week", "start": "08:00", "end": "10:00", "minSpacing": "30m", "seed": 3}`, periods: 4, days: 7},
+		{raw: `{"times": 4, "start": "22:00", "end": "23:00", "minSpacing": "15m", "timeZone": "Europe/Berlin", "seed": 4}`, periods: 14, days: 1},
+	}
+
+	for _, test := range tests {
+		var s RandomSchedule
+		if err := json.Unmarshal([]byte(test.raw), &s); err != nil {
+			t.Fatalf("Unmarshal(%s) = %v", test.raw, err)
+		}
+
+		// start at the beginning of a period, on Monday 2024-03-25, spanning a DST change in Berlin
+		periodStart := s.periodStart(time.Date(2024, 3, 25, 12, 0, 0, 0, s.loc))
+		end := periodStart.AddDate(0, 0, test.periods*test.days)
+		counts := make(map[time.Time]int)
+		var last time.Time
+		for next := s.Next(periodStart.Add(-time.Nanosecond)); next.Before(end); next = s.Next(next) {
+			if !next.After(last) {
+				t.Fatalf("%s: Next() = %s, not after %s", test.raw, next, last)
+			}
+			wall := next.In(s.loc)
+			offset := time.Duration(wall.Hour())*time.Hour + time.Duration(wall.Minute())*time.Minute
+			if offset < s.start || offset >= s.end {
+				t.Errorf("%s: %s is outside the window", test.raw, wall)
+			}
+			period := s.periodStart(next)
+			if counts[period] > 0 && next.Sub(last) < s.spacing {
+				t.Errorf("%s: %s is within %s of %s", test.raw, next, s.spacing, last)
+			}
+			counts[period]++
+			last = next
+		}
+
+		if len(counts) != test.periods {
+			t.Errorf("%s: barked in %d periods, want %d", test.raw, len(counts), test.periods)
+		}
+		for period, count := range counts {
+			if count != s.Times {
+				t.Errorf("%s: barked %d times in period %s, want %d", test.raw, count, period, s.Times)
+			}
+		}
+
+		// the same seed produces the same times
+		var again RandomSchedule
+		json.Unmarshal([]byte(test.raw), &again)
+		if a, b := s.Next(periodStart), again.Next(periodStart); !a.Equal(b) {
+			t.Errorf("%s: Next() = %s and %s with the same seed", test.raw, a, b)
+		}
+	}
+}
+
+func TestRandomScheduleJSON(t *testing.T) {
+	var s RandomSchedule
+	if err := json.Unmarshal([]byte(`{"times": 2}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Seed == 0 || s.Per != "day" {
+		t.Errorf("schedule = %+v, want generated seed and daily period", s)
+	}
+
+	for _, raw := range []string{
+		`{"times": 0}`,
+		`{"times": 101}`,
+		`{"times": 1, "per": "month"}`,
+		`{"times": 1, "start": "9am"}`,
+		`{"times": 1, "start": "17:00", "end": "09:00"}`,
+		`{"times": 1, "minSpacing": "-1h"}`,
+		`{"times": 5, "start": "09:00", "end": "10:00", "minSpacing": "15m"}`,
+		`{"times": 1, "timeZone": "Nowhere/Special"}`,
+	} {
+		if err := json.Unmarshal([]byte(raw), &s); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", raw)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package dog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Spaced repetition parameters, as in the SM-2 algorithm.
+const (
+	initialEase   = 2.5
+	minEase       = 1.3
+	maxRating     = 5
+	passingRating = 3
+)
+
+// Repetition is the spaced repetition state of a dog.
+type Repetition struct {
+	Ease        float64       `json:"ease" firestore:"ease"`
+	Interval    time.Duration `json:"interval" firestore:"interval"`
+	Repetitions int           `json:"repetitions" firestore:"repetitions"`
+	ReviewTime  time.Time     `json:"reviewTime" firestore:"reviewTime"`
+}
+
+// SpacedSchedule is a spaced repetition schedule. The schedule fires one interval after the last
+// review, and repeats at that interval until the next review. Reviews change the interval based
+// on the user's recall of the idea, following the SM-2 algorithm.
+type SpacedSchedule struct {
+	FirstInterval  time.Duration
+	SecondInterval time.Duration
+	MaxInterval    time.Duration
+	Repetition     Repetition
+}
+
+func init() {
+	RegisterScheduleType("spaced", func(b json.RawMessage) (Schedule, error) {
+		var schedule SpacedSchedule
+		err := json.Unmarshal(b, &schedule)
+		return schedule, err
+	})
+}
+
+// spacedScheduleJSON is the JSON form of a SpacedSchedule.
+type spacedScheduleJSON struct {
+	FirstInterval  string `json:"firstInterval,omitempty"`
+	SecondInterval string `json:"secondInterval,omitempty"`
+	MaxInterval    string `json:"maxInterval,omitempty"`
+}
+
+// Next implements the Schedule interface.
+// A schedule without repetition state, as when parsed from a request, fires one first interval
+// after t.
+func (s SpacedSchedule) Next(t time.Time) time.Time {
+	rep := s.Repetition
+	if rep.Interval <= 0 {
+		rep = s.initial(t)
+	}
+	next := rep.ReviewTime.Add(rep.Interval)
+	if !next.After(t) {
+		next = next.Add((t.Sub(next)/rep.Interval + 1) * rep.Interval)
+	}
+	return next
+}
+
+// Review returns the repetition state after a review at time t with a recall rating from 0 to 5.
+func (s SpacedSchedule) Review(rep Repetition, rating int, t time.Time) Repetition {
+	if rating >= passingRating {
+		switch rep.Repetitions {
+		case 0:
+			rep.Interval = s.FirstInterval
+		case 1:
+			rep.Interval = s.SecondInterval
+		default:
+			rep.Interval = time.Duration(math.Round(float64(rep.Interval) * rep.Ease))
+		}
+		rep.Repetitions++
+	} else {
+		rep.Interval = s.FirstInterval
+		rep.Repetitions = 0
+	}
+	if rep.Interval > s.MaxInterval {
+		rep.Interval = s.MaxInterval
+	}
+
+	miss := float64(maxRating - rating)
+	rep.Ease = math.Max(minEase, rep.Ease+0.1-miss*(0.08+miss*0.02))
+	rep.ReviewTime = t
+	return rep
+}
+
+// initial returns the repetition state of a dog that has not been reviewed.
+func (s SpacedSchedule) initial(creationTime time.Time) Repetition {
+	return Repetition{
+		Ease:       initialEase,
+		Interval:   s.FirstInterval,
+		ReviewTime: creationTime,
+	}
+}
+
+// UnmarshalJSON parses a JSON object with optional "firstInterval", "secondInterval" and
+// "maxInterval" intervals, which default to 1, 6 and 365 days.
+func (s *SpacedSchedule) UnmarshalJSON(b []byte) error {
+	raw := spacedScheduleJSON{
+		FirstInterval:  "1d",
+		SecondInterval: "6d",
+		MaxInterval:    "365d",
+	}
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	if s.FirstInterval, err = parseInterval(raw.FirstInterval); err != nil {
+		return err
+	}
+	if s.SecondInterval, err = parseInterval(raw.SecondInterval); err != nil {
+		return err
+	}
+	if s.MaxInterval, err = parseInterval(raw.MaxInterval); err != nil {
+		return err
+	}
+
+	if s.FirstInterval < MinInterval {
+		return fmt.Errorf("interval must be at least %s", MinInterval)
+	}
+	if s.SecondInterval < s.FirstInterval || s.MaxInterval < s.SecondInterval {
+		return errors.New("intervals must not decrease")
+	}
+	return nil
+}
+
+// MarshalJSON writes the intervals as a JSON object.
+func (s SpacedSchedule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&spacedScheduleJSON{
+		FirstInterval:  formatInterval(s.FirstInterval),
+		SecondInterval: formatInterval(s.SecondInterval),
+		MaxInterval:    formatInterval(s.MaxInterval),
+	})
+}
+
+var maxFeedbackRequestSizeBytes int64 = 1000
+
+// errNotSpaced is returned when reviewing a dog that does not have a spaced repetition schedule.
+var errNotSpaced = errors.New("dog does not have a spaced repetition schedule")
+
+// FeedbackRequest is the request type for reviewing a dog's idea.
+type FeedbackRequest struct {
+	// Rating is the user's recall of the idea, from 0 (forgotten) to 5 (perfect recall).
+	Rating *int `json:"rating"`
+}
+
+// PostFeedback is a handler for reviewing the idea of a dog with a spaced repetition schedule.
+// The dog is rescheduled based on the rating, superseding its pending task.
+func (service *Service) PostFeedback(w http.ResponseWriter, r *http.Request) {
+	dogID := chi.URLParam(r, "dogID")
+	var requestBody FeedbackRequest
+
+	// read request body into struct
+	r.Body = http.MaxBytesReader(w, r.Body, maxFeedbackRequestSizeBytes)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		service.Logf(r, `result=DecodeError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if requestBody.Rating == nil || *requestBody.Rating < 0 || *requestBody.Rating > maxRating {
+		service.Logf(r, `result=InvalidRatingError`)
+		bark.RespondError(w, http.StatusBadRequest,
+			fmt.Sprintf("rating must be between 0 and %d", maxRating))
+		return
+	}
+
+	// review in a transaction, so that concurrent reviews and barks are not lost, and reschedule
+	// from now rather than after the pending task
+	rating := *requestBody.Rating
+	reviewTime := time.Now()
+	dog, err := service.TasksClient.Revise(r.Context(), dogID,
+		func(dog *Dog) (map[string]interface{}, error) {
+			schedule, err := ParseSchedule(dog.ScheduleType, dog.ScheduleRaw)
+			if err != nil {
+				return nil, err
+			}
+			spaced, ok := schedule.(SpacedSchedule)
+			if !ok {
+				return nil, errNotSpaced
+			}
+			rep := spaced.Review(dog.repetition(spaced), rating, reviewTime)
+			dog.Repetition = &rep
+			return map[string]interface{}{"repetition": dog.Repetition}, nil
+		})
+	switch {
+	case err == nil:
+		service.Logf(r, `action=ReviseDog dogID=%s rating=%d nextTaskTime=%s result=OK`,
+			dogID, rating, dog.NextTaskTime.Format(time.RFC3339))
+	case err == errNotSpaced:
+		service.Logf(r, `dogID=%s result=NotSpacedError`, dogID)
+		bark.RespondError(w, http.StatusConflict, err.Error())
+		return
+	case status.Code(err) == codes.NotFound:
+		service.Logf(r, `action=ReviseDog dogID=%s result=NotFoundError`, dogID)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("dog not found with ID: ", dogID))
+		return
+	default:
+		service.Logf(r, `action=ReviseDog dogID=%s result=InternalError errorText="%s"`,
+			dogID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	bark.RespondSuccess(w, http.StatusOK, dog)
+}
@@ -0,0 +1,186 @@
+package dog
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy determines how failed deliveries are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of delivery attempts, including the first attempt.
+	// Barks that fail their last attempt are moved to the dead letters.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy retries deliveries up to four times over roughly fifteen minutes.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Minute,
+	MaxDelay:    time.Hour,
+}
+
+// Delay returns the delay before retrying a failed attempt. The delay doubles with each attempt up
+// to MaxDelay, and is jittered between half and all of that value.
+func (policy RetryPolicy) Delay(attempt int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 1; i < attempt && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	half := int64(delay / 2)
+	jitter := rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(half + 1)
+	return time.Duration(half + jitter)
+}
+
+// retryLater schedules a retry of a failed bark, or moves it to the dead letters if it has no
+// attempts remaining or the retry cannot be scheduled.
+func (service *Service) retryLater(r *http.Request, record *BarkRecord) {
+	if record.Attempt < service.RetryPolicy.MaxAttempts {
+		retryTime := time.Now().Add(service.RetryPolicy.Delay(record.Attempt))
+		err := service.TasksClient.ScheduleRetry(r.Context(), record, retryTime)
+		if err == nil {
+			service.Logf(r, `action=ScheduleRetry barkID=%s attempt=%d retryTime=%s result=OK`,
+				record.ID, record.Attempt+1, retryTime.Format(time.RFC3339))
+			return
+		}
+		service.Logf(r, `action=ScheduleRetry barkID=%s attempt=%d result=InternalError errorText="%s"`,
+			record.ID, record.Attempt+1, err)
+	}
+
+	err := service.DeadLetters.Put(r.Context(), record)
+	if err != nil {
+		service.Logf(r, `action=PutDeadLetter barkID=%s result=InternalError errorText="%s"`,
+			record.ID, err)
+		return
+	}
+	service.Logf(r, `action=PutDeadLetter barkID=%s result=OK`, record.ID)
+}
+
+// redeliver makes another delivery attempt of a recorded bark and updates its history record.
+func (service *Service) redeliver(r *http.Request, dog *Dog, idea *bark.Idea,
+	record *BarkRecord, attempt int) *BarkRecord {
+
+	record = service.deliver(r, &Bark{
+		ID:            record.ID,
+		Dog:           dog,
+		Idea:          idea,
+		Address:       record.Address,
+		Secret:        dog.deliverySecret(record.Channel, record.Address),
+		TaskName:      record.TaskName,
+		ScheduledTime: record.ScheduledTime,
+		Attempt:       attempt,
+	}, record.Channel)
+
+	if err := service.BarkStore.Update(r.Context(), record); err != nil {
+		service.Logf(r, `action=UpdateBarkRecord barkID=%s result=InternalError errorText="%s"`,
+			record.ID, err)
+	}
+	if record.Outcome == BarkDelivered {
+		service.Hub.Publish(record)
+	}
+	return record
+}
+
+// RetryBark is a handler for retrying delivery of a failed bark.
+// This endpoint is called by the retry tasks created for failed barks.
+func (service *Service) RetryBark(w http.ResponseWriter, r *http.Request) {
+	dogID := chi.URLParam(r, "dogID")
+	barkID := chi.URLParam(r, "barkID")
+
+	if r.Header.Get(TaskNameHeader) == "" {
+		service.Logf(r, `result=MissingTaskNameError`)
+		bark.RespondError(w, http.StatusBadRequest, "missing task name")
+		return
+	}
+	attempt, err := strconv.Atoi(r.URL.Query().Get("attempt"))
+	if err != nil || attempt < 2 {
+		service.Logf(r, `result=InvalidAttemptError`)
+		bark.RespondError(w, http.StatusBadRequest, "invalid attempt")
+		return
+	}
+
+	// get bark record
+	record, err := service.BarkStore.Get(r.Context(), barkID)
+	switch {
+	case err == nil && record.DogID == dogID:
+		service.Logf(r, `action=GetBarkRecord barkID=%s result=OK`, barkID)
+	case err == nil || status.Code(err) == codes.NotFound:
+		service.Logf(r, `action=GetBarkRecord barkID=%s result=NotFoundError`, barkID)
+		bark.RespondError(w, http.StatusNotFound, "bark not found with ID: "+barkID)
+		return
+	default:
+		service.Logf(r, `action=GetBarkRecord barkID=%s result=InternalError errorText="%s"`,
+			barkID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	// ignore duplicate retries
+	if record.Outcome == BarkDelivered || record.Attempt >= attempt {
+		service.Logf(r, `action=RetryBark barkID=%s attempt=%d result=Ignored`, barkID, attempt)
+		bark.RespondSuccess(w, http.StatusNoContent, nil)
+		return
+	}
+
+	// barks of deleted dogs and ideas are dropped
+	dog, idea, err := service.getBarkTarget(r, record)
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.NotFound:
+		bark.RespondSuccess(w, http.StatusNoContent, nil)
+		return
+	default:
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	record = service.redeliver(r, dog, idea, record, attempt)
+	if record.Outcome == BarkFailed {
+		service.retryLater(r, record)
+	}
+
+	bark.RespondSuccess(w, http.StatusOK, record)
+}
+
+// getBarkTarget gets and logs the dog and idea of a recorded bark.
+func (service *Service) getBarkTarget(r *http.Request, record *BarkRecord) (*Dog, *bark.Idea, error) {
+	dog, err := service.DogGetter.Get(r.Context(), record.DogID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetDog dogID=%s result=OK`, record.DogID)
+	case codes.NotFound:
+		service.Logf(r, `action=GetDog dogID=%s result=NotFoundError`, record.DogID)
+		return nil, nil, err
+	default:
+		service.Logf(r, `action=GetDog dogID=%s result=InternalError errorText="%s"`,
+			record.DogID, err)
+		return nil, nil, err
+	}
+
+	idea, err := service.IdeaGetter.Get(r.Context(), record.IdeaID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetIdea ideaID=%s result=OK`, record.IdeaID)
+	case codes.NotFound:
+		service.Logf(r, `action=GetIdea ideaID=%s result=NotFoundError`, record.IdeaID)
+		return nil, nil, err
+	default:
+		service.Logf(r, `action=GetIdea ideaID=%s result=InternalError errorText="%s"`,
+			record.IdeaID, err)
+		return nil, nil, err
+	}
+
+	return dog, idea, nil
+}
@@ -0,0 +1,70 @@
+package dog
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+// RRuleSchedule is a schedule of RFC 5545 recurrence rules.
+type RRuleSchedule struct {
+	set *rrule.Set
+}
+
+func init() {
+	RegisterScheduleType("rrule", func(b json.RawMessage) (Schedule, error) {
+		var schedule RRuleSchedule
+		err := json.Unmarshal(b, &schedule)
+		return schedule, err
+	})
+}
+
+// Next implements the Schedule interface.
+func (s RRuleSchedule) Next(t time.Time) time.Time {
+	return s.set.After(t, false)
+}
+
+// UnmarshalJSON parses a JSON string of newline-separated lines, or a JSON array of lines, into an
+// RRuleSchedule. Lines may be DTSTART, RRULE, RDATE and EXDATE properties, with DTSTART first.
+// DTSTART defaults to the current minute in UTC.
+func (s *RRuleSchedule) UnmarshalJSON(b []byte) error {
+	var lines []string
+	var recurrence string
+	if err := json.Unmarshal(b, &recurrence); err == nil {
+		lines = strings.Split(recurrence, "\n")
+	} else if err = json.Unmarshal(b, &lines); err != nil {
+		return err
+	}
+
+	var properties []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			properties = append(properties, line)
+		}
+	}
+	if len(properties) == 0 {
+		return errors.New("missing recurrence rule")
+	}
+	if !strings.HasPrefix(strings.ToUpper(properties[0]), "DTSTART") {
+		dtstart := time.Now().UTC().Truncate(time.Minute).Format("20060102T150405Z")
+		properties = append([]string{"DTSTART:" + dtstart}, properties...)
+	}
+
+	set, err := rrule.StrSliceToRRuleSet(properties)
+	if err != nil {
+		return err
+	}
+	if rule := set.GetRRule(); rule != nil && rule.OrigOptions.Freq == rrule.SECONDLY {
+		return errors.New("secondly recurrence is not supported")
+	}
+	s.set = set
+	return nil
+}
+
+// MarshalJSON writes the recurrence as a JSON string of newline-separated lines.
+func (s RRuleSchedule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.set.String())
+}
@@ -0,0 +1,93 @@
+package dog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRRuleScheduleNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "weekly on weekdays",
+			raw:   `"DTSTART:20240101T090000Z\nRRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR"`,
+			after: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "array of lines",
+			raw:   `["DTSTART:20240101T090000Z", "RRULE:FREQ=MONTHLY;BYDAY=-1FR"]`,
+			after: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 1, 26, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "local start time",
+			raw:   `"DTSTART;TZID=America/New_York:20240101T090000\nRRULE:FREQ=DAILY"`,
+			after: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC), // 9am EDT
+		},
+		{
+			name:  "excluded date",
+			raw:   `"DTSTART:20240101T090000Z\nRRULE:FREQ=DAILY\nEXDATE:20240102T090000Z"`,
+			after: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "extra date",
+			raw:   `"DTSTART:20240101T090000Z\nRRULE:FREQ=WEEKLY\nRDATE:20240103T120000Z"`,
+			after: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "after last occurrence",
+			raw:   `"DTSTART:20240101T090000Z\nRRULE:FREQ=DAILY;COUNT=3"`,
+			after: time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC),
+			want:  time.Time{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseSchedule("rrule", json.RawMessage(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(test.after); !got.Equal(test.want) {
+				t.Errorf("Next(%s) = %s, want %s", test.after, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRRuleScheduleJSON(t *testing.T) {
+	// a rule without a start starts at the current minute
+	var s RRuleSchedule
+	before := time.Now().Truncate(time.Minute)
+	if err := json.Unmarshal([]byte(`"RRULE:FREQ=HOURLY"`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if next := s.Next(before.Add(-time.Second)); !next.Equal(before) && !next.Equal(before.Add(time.Minute)) {
+		t.Errorf("Next() = %s, want the current minute %s", next, before)
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil || !strings.Contains(string(b), `RRULE:FREQ=HOURLY`) {
+		t.Errorf("Marshal() = %s, %v", b, err)
+	}
+	var again RRuleSchedule
+	if err = json.Unmarshal(b, &again); err != nil {
+		t.Errorf("Unmarshal(%s) = %v", b, err)
+	}
+
+	for _, raw := range []string{`""`, `"\n \n"`, `"RRULE:FREQ=SECONDLY"`, `"RRULE:FREQ=SOMETIMES"`, `42`} {
+		if err := json.Unmarshal([]byte(raw), &s); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", raw)
+		}
+	}
+}
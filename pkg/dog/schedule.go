@@ -3,26 +3,58 @@ package dog
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 )
 
 // Schedule defines when a Dog barks.
+// Next returns the zero time if the schedule has no times after t.
 type Schedule interface {
 	Next(time.Time) time.Time
 }
 
+// A ScheduleFactory parses the raw JSON of a schedule type into a Schedule.
+type ScheduleFactory func(b json.RawMessage) (Schedule, error)
+
 // ErrScheduleTypeNotSupported is returned by ParseSchedule when the requested schedule type is
 // not supported.
 var ErrScheduleTypeNotSupported = errors.New("schedule type not supported")
 
-// ParseSchedule parses raw JSON for a given schedule type.
+var (
+	scheduleTypesMu sync.RWMutex
+	scheduleTypes   = make(map[string]ScheduleFactory)
+)
+
+// RegisterScheduleType registers the factory for a schedule type, replacing any factory already
+// registered for the type.
+func RegisterScheduleType(scheduleType string, factory ScheduleFactory) {
+	scheduleTypesMu.Lock()
+	defer scheduleTypesMu.Unlock()
+	scheduleTypes[scheduleType] = factory
+}
+
+// ScheduleTypes returns the sorted names of all registered schedule types.
+func ScheduleTypes() []string {
+	scheduleTypesMu.RLock()
+	defer scheduleTypesMu.RUnlock()
+	names := make([]string, 0, len(scheduleTypes))
+	for name := range scheduleTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSchedule parses raw JSON for a given schedule type. An error wrapping
+// ErrScheduleTypeNotSupported is returned if the type is not registered.
 func ParseSchedule(scheduleType string, b json.RawMessage) (Schedule, error) {
-	switch scheduleType {
-	case "cron":
-		var schedule CronSchedule
-		err := json.Unmarshal(b, &schedule)
-		return schedule, err
-	default:
-		return nil, ErrScheduleTypeNotSupported
+	scheduleTypesMu.RLock()
+	factory, found := scheduleTypes[scheduleType]
+	scheduleTypesMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrScheduleTypeNotSupported, scheduleType)
 	}
+	return factory(b)
 }
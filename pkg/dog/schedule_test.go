@@ -0,0 +1,76 @@
+package dog_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/dog"
+)
+
+// everyMinutes is a schedule type registered from outside the dog package.
+type everyMinutes struct {
+	Minutes int `json:"minutes"`
+}
+
+func (s everyMinutes) Next(t time.Time) time.Time {
+	return t.Truncate(time.Minute).Add(time.Duration(s.Minutes) * time.Minute)
+}
+
+func init() {
+	dog.RegisterScheduleType("test-minutes", func(b json.RawMessage) (dog.Schedule, error) {
+		var s everyMinutes
+		if err := json.Unmarshal(b, &s); err != nil {
+			return nil, err
+		}
+		if s.Minutes < 1 {
+			return nil, errors.New("minutes must be positive")
+		}
+		return s, nil
+	})
+}
+
+func TestRegisteredScheduleType(t *testing.T) {
+	found := false
+	for _, scheduleType := range dog.ScheduleTypes() {
+		found = found || scheduleType == "test-minutes"
+	}
+	if !found {
+		t.Errorf("ScheduleTypes() = %v, want test-minutes", dog.ScheduleTypes())
+	}
+
+	d := &dog.Dog{ScheduleType: "test-minutes", ScheduleRaw: json.RawMessage(`{"minutes": 15}`)}
+	schedule, err := d.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 1, 9, 0, 30, 0, time.UTC)
+	if next, want := schedule.Next(now), now.Truncate(time.Minute).Add(15*time.Minute); !next.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", now, next, want)
+	}
+
+	// the registered type is accepted and validated by handlers
+	service := &dog.Service{TasksClient: dog.Whisperer{}}
+	w := serve(service, http.MethodPost, "/schedules/preview",
+		`{"scheduleType": "test-minutes", "schedule": {"minutes": 15}, "count": 2}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("preview status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	w = serve(service, http.MethodPost, "/schedules/preview",
+		`{"scheduleType": "test-minutes", "schedule": {"minutes": 0}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("preview status = %d for invalid schedule, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUnknownScheduleType(t *testing.T) {
+	d := &dog.Dog{ScheduleType: "retired", ScheduleRaw: json.RawMessage(`{}`)}
+	if _, err := d.Schedule(); !errors.Is(err, dog.ErrScheduleTypeNotSupported) {
+		t.Errorf("Schedule() = %v, want error wrapping %v", err, dog.ErrScheduleTypeNotSupported)
+	}
+	if _, err := dog.ParseSchedule("retired", json.RawMessage(`{}`)); !errors.Is(err, dog.ErrScheduleTypeNotSupported) {
+		t.Errorf("ParseSchedule() = %v, want error wrapping %v", err, dog.ErrScheduleTypeNotSupported)
+	}
+}
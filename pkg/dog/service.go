@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/dgravesa/bark/pkg/bark"
@@ -20,6 +21,18 @@ type Service struct {
 	IdeaGetter  IdeaGetter
 	DogGetter   DoggoGetter
 	TasksClient TasksClient
+	Barkers     *BarkerRegistry
+	BarkStore   BarkStore
+	RetryPolicy RetryPolicy
+	DeadLetters DeadLetterStore
+	UserStore   UserStore
+	Hub         *BarkHub
+	Inbox       InboxStore
+	Digests     DigestStore
+	Calendars   CalendarStore
+	// AdminToken is the bearer token required by admin endpoints. Admin endpoints refuse all
+	// requests if it is empty.
+	AdminToken string
 }
 
 // IdeaGetter is an interface for getting ideas.
@@ -35,26 +48,103 @@ type DoggoGetter interface {
 // TasksClient is a client interface to tasks.
 type TasksClient interface {
 	Register(ctx context.Context, dog *Dog) (*Dog, error)
+	Reschedule(ctx context.Context, dog *Dog) (*Dog, error)
+	Revise(ctx context.Context, dogID string,
+		revise func(dog *Dog) (map[string]interface{}, error)) (*Dog, error)
+	Schedule(ctx context.Context, dog *Dog) (Schedule, error)
+	Claim(ctx context.Context, dogID, taskName string) (*Dog, error)
+	Release(ctx context.Context, dogID, taskName string) error
+	ScheduleRetry(ctx context.Context, record *BarkRecord, scheduleTime time.Time) error
+	ScheduleFollowUp(ctx context.Context, item *InboxItem, scheduleTime time.Time) error
+	RegisterDigest(ctx context.Context, digest *Digest) (*Digest, error)
+	RescheduleDigest(ctx context.Context, digest *Digest) (*Digest, error)
+	UnregisterDigest(ctx context.Context, digestID string) error
+	ClaimDigest(ctx context.Context, digestID, taskName string) (*Digest, error)
 	Unregister(ctx context.Context, dogID string) error
 }
 
-// RegisterRoutes registers service routes to a chi mux instance.
-func (service *Service) RegisterRoutes(r *chi.Mux) {
+// TaskNameHeader is the request header in which Cloud Tasks sends the short name of the task.
+const TaskNameHeader = "X-AppEngine-TaskName"
+
+// RegisterRoutes registers service routes to a chi router.
+func (service *Service) RegisterRoutes(r chi.Router) {
 	r.Route("/dogs", func(r chi.Router) {
 		r.Post("/", service.PostDog)
 
 		r.Route("/{dogID}", func(r chi.Router) {
 			r.Get("/", service.GetDog)
 			r.Delete("/", service.DeleteDog)
+			r.Post("/bark", service.BarkDog)
+			r.Post("/feedback", service.PostFeedback)
+			r.Get("/barks", service.ListDogBarks)
+			r.Get("/upcoming", service.GetUpcoming)
+			r.Post("/barks/{barkID}/retry", service.RetryBark)
+		})
+	})
+
+	r.Post("/schedules/preview", service.PreviewSchedule)
+
+	r.Route("/calendars", func(r chi.Router) {
+		r.Post("/", service.PostCalendar)
+
+		r.Route("/{calendarID}", func(r chi.Router) {
+			r.Get("/", service.GetCalendar)
+			r.Delete("/", service.DeleteCalendar)
+			r.Put("/holidays", service.PutCalendarHolidays)
+		})
+	})
+
+	r.Route("/digests", func(r chi.Router) {
+		r.Post("/", service.PostDigest)
+
+		r.Route("/{digestID}", func(r chi.Router) {
+			r.Get("/", service.GetDigest)
+			r.Delete("/", service.DeleteDigest)
+			r.Post("/send", service.SendDigest)
+		})
+	})
+
+	r.Route("/inbox", func(r chi.Router) {
+		r.Get("/", service.GetInbox)
+
+		r.Route("/{barkID}", func(r chi.Router) {
+			r.Post("/ack", service.AckInboxItem)
+			r.Post("/snooze", service.SnoozeInboxItem)
+			r.Post("/resurface", service.ResurfaceInboxItem)
 		})
 	})
+
+	r.Route("/users/{userID}/quiet-hours", func(r chi.Router) {
+		r.Get("/", service.GetQuietHours)
+		r.Put("/", service.PutQuietHours)
+	})
+	r.Put("/users/{userID}/digest", service.PutUserDigest)
+
+	r.Route("/admin/dead-letters", func(r chi.Router) {
+		r.Use(service.requireAdmin)
+		r.Get("/", service.ListDeadLetters)
+		r.Post("/{barkID}/replay", service.ReplayDeadLetter)
+	})
 }
 
 // CreateDogRequest is the request type for creating a new Dog.
 type CreateDogRequest struct {
 	IdeaID       string          `json:"ideaId,omitempty"`
+	UserID       string          `json:"userId,omitempty"`
 	ScheduleType string          `json:"scheduleType"`
 	Schedule     json.RawMessage `json:"schedule"`
+	Deliveries   []Delivery      `json:"deliveries,omitempty"`
+	QuietHours   QuietHours      `json:"quietHours,omitempty"`
+	DigestID     string          `json:"digestId,omitempty"`
+	Bounds       *Bounds         `json:"bounds,omitempty"`
+	CalendarID   string          `json:"calendarId,omitempty"`
+}
+
+// CreateDogResponse is the response type for creating a new Dog. It holds the secrets generated
+// for the dog's deliveries, which are not returned again.
+type CreateDogResponse struct {
+	*Dog
+	Secrets []DeliverySecret `json:"secrets,omitempty"`
 }
 
 var maxCreateDogRequestSizeBytes int64 = 20000
@@ -73,13 +163,41 @@ func (service *Service) PostDog(w http.ResponseWriter, r *http.Request) {
 	}
 	ideaID := requestBody.IdeaID
 
-	_, err = ParseSchedule(requestBody.ScheduleType, requestBody.Schedule)
+	scheduleRaw, ok := service.parseSchedule(w, r, requestBody.ScheduleType, requestBody.Schedule,
+		requestBody.Bounds, requestBody.QuietHours)
+	if !ok {
+		return
+	}
+
+	// verify delivery channels exist
+	for _, delivery := range requestBody.Deliveries {
+		if _, err = service.Barkers.Get(delivery.Channel); err != nil {
+			service.Logf(r, `result=ChannelNotRegisteredError channel=%s`, delivery.Channel)
+			bark.RespondError(w, http.StatusBadRequest,
+				fmt.Sprint("delivery channel not registered: ", delivery.Channel))
+			return
+		}
+	}
+
+	secrets, err := service.Barkers.GenerateSecrets(requestBody.Deliveries)
 	if err != nil {
-		service.Logf(r, `result=ParseScheduleError errorText="%s"`, err)
-		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		service.Logf(r, `action=GenerateSecrets result=InternalError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
 		return
 	}
 
+	// verify digest exists
+	if requestBody.DigestID != "" && !service.digestExists(w, r, requestBody.DigestID) {
+		return
+	}
+
+	// verify calendar exists
+	if requestBody.CalendarID != "" {
+		if _, ok := service.getCalendar(w, r, requestBody.CalendarID); !ok {
+			return
+		}
+	}
+
 	// verify target exists
 	_, err = service.IdeaGetter.Get(r.Context(), ideaID)
 	switch status.Code(err) {
@@ -101,19 +219,26 @@ func (service *Service) PostDog(w http.ResponseWriter, r *http.Request) {
 		ID:           uuid.NewString(),
 		CreationTime: time.Now(),
 		IdeaID:       ideaID,
+		UserID:       requestBody.UserID,
 		ScheduleType: requestBody.ScheduleType,
-		ScheduleRaw:  requestBody.Schedule,
+		ScheduleRaw:  scheduleRaw,
+		Deliveries:   requestBody.Deliveries,
+		QuietHours:   requestBody.QuietHours,
+		DigestID:     requestBody.DigestID,
+		Bounds:       requestBody.Bounds,
+		CalendarID:   requestBody.CalendarID,
 	}
 
 	dog, err = service.TasksClient.Register(r.Context(), dog)
 	if err != nil {
-		service.Logf(r, `action=RegisterDog dogID=%s ideaID=%s result=InternalError errorText="%s"`, err)
+		service.Logf(r, `action=RegisterDog dogID=%s ideaID=%s result=InternalError errorText="%s"`,
+			dog.ID, dog.IdeaID, err)
 		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
 		return
 	}
 	service.Logf(r, `action=RegisterDog dogID=%s ideaID=%s result=OK`, dog.ID, dog.IdeaID)
 
-	bark.RespondSuccess(w, http.StatusCreated, dog)
+	bark.RespondSuccess(w, http.StatusCreated, &CreateDogResponse{Dog: dog, Secrets: secrets})
 }
 
 // GetDog is a handler for getting a dog.
@@ -151,3 +276,266 @@ func (service *Service) DeleteDog(w http.ResponseWriter, r *http.Request) {
 	service.Logf(r, `action=UnregisterDog dogID=%s result=OK`, dogID)
 	bark.RespondSuccess(w, http.StatusNoContent, nil)
 }
+
+// BarkDog is a handler for barking a dog's idea and scheduling its next bark.
+// This endpoint is called by the tasks created for a dog.
+//
+// Tasks may be delivered more than once, so the executing task is claimed against the dog's next
+// task before barking. Stale tasks are acknowledged without barking. Tasks claimed by another
+// execution are answered with a conflict so that they are retried, and may be claimed again if
+// that execution ends without finishing once its claim's lease expires.
+func (service *Service) BarkDog(w http.ResponseWriter, r *http.Request) {
+	dogID := chi.URLParam(r, "dogID")
+
+	taskName := r.Header.Get(TaskNameHeader)
+	if taskName == "" {
+		service.Logf(r, `result=MissingTaskNameError`)
+		bark.RespondError(w, http.StatusBadRequest, "missing task name")
+		return
+	}
+
+	// claim task for execution
+	dog, err := service.TasksClient.Claim(r.Context(), dogID, taskName)
+	switch {
+	case err == nil:
+		service.Logf(r, `action=ClaimTask dogID=%s taskName=%s result=OK`, dogID, taskName)
+	case err == ErrTaskStale:
+		service.Logf(r, `action=ClaimTask dogID=%s taskName=%s result=Ignored reason="%s"`,
+			dogID, taskName, err)
+		bark.RespondSuccess(w, http.StatusNoContent, nil)
+		return
+	case err == ErrTaskClaimed:
+		service.Logf(r, `action=ClaimTask dogID=%s taskName=%s result=ConflictError`, dogID, taskName)
+		bark.RespondError(w, http.StatusConflict, "task is being executed")
+		return
+	case status.Code(err) == codes.NotFound:
+		service.Logf(r, `action=ClaimTask dogID=%s taskName=%s result=NotFoundError`, dogID, taskName)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("dog not found with ID: ", dogID))
+		return
+	default:
+		service.Logf(r, `action=ClaimTask dogID=%s taskName=%s result=InternalError errorText="%s"`,
+			dogID, taskName, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	// resolve idea
+	idea, err := service.IdeaGetter.Get(r.Context(), dog.IdeaID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetIdea ideaID=%s result=OK`, dog.IdeaID)
+	case codes.NotFound:
+		service.Logf(r, `action=GetIdea ideaID=%s result=NotFoundError`, dog.IdeaID)
+		service.release(r, dog.ID, taskName)
+		bark.RespondError(w, http.StatusNotFound, fmt.Sprint("idea not found with ID: ", dog.IdeaID))
+		return
+	default:
+		service.Logf(r, `action=GetIdea ideaID=%s result=InternalError errorText="%s"`,
+			dog.IdeaID, err)
+		service.release(r, dog.ID, taskName)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	// schedule next bark before delivering so that a failed delivery never stops the dog
+	executingTaskName := dog.NextTaskName
+	scheduledTime := dog.NextTaskTime
+	dog.BarkCount++
+	dog, err = service.TasksClient.Reschedule(r.Context(), dog)
+	if err != nil {
+		service.Logf(r, `action=RescheduleDog dogID=%s result=InternalError errorText="%s"`,
+			dog.ID, err)
+		service.release(r, dog.ID, taskName)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	if dog.Completed {
+		service.Logf(r, `action=CompleteDog dogID=%s result=OK`, dog.ID)
+	} else {
+		service.Logf(r, `action=RescheduleDog dogID=%s nextTaskTime=%s result=OK`,
+			dog.ID, dog.NextTaskTime.Format(time.RFC3339))
+	}
+
+	// collect idea into digest if the dog is in digest mode
+	if digestID := service.digestID(r, dog); digestID != "" {
+		err = service.collect(r, digestID, &Bark{
+			ID:            uuid.NewString(),
+			Dog:           dog,
+			Idea:          idea,
+			TaskName:      executingTaskName,
+			ScheduledTime: scheduledTime,
+			Attempt:       1,
+		})
+		if err == nil {
+			bark.RespondSuccess(w, http.StatusOK, dog)
+			return
+		}
+		// deliver individually rather than lose the bark
+	}
+
+	// deliver idea to each of the dog's channels
+	for _, delivery := range service.Barkers.Deliveries(dog) {
+		record := service.deliver(r, &Bark{
+			ID:            uuid.NewString(),
+			Dog:           dog,
+			Idea:          idea,
+			Address:       delivery.Address,
+			Secret:        delivery.Secret,
+			TaskName:      executingTaskName,
+			ScheduledTime: scheduledTime,
+			Attempt:       1,
+		}, delivery.Channel)
+
+		if err = service.BarkStore.Put(r.Context(), record); err != nil {
+			service.Logf(r, `action=PutBarkRecord barkID=%s result=InternalError errorText="%s"`,
+				record.ID, err)
+		}
+		if record.Outcome == BarkFailed {
+			service.retryLater(r, record)
+		} else {
+			service.Hub.Publish(record)
+		}
+	}
+
+	bark.RespondSuccess(w, http.StatusOK, dog)
+}
+
+// release releases a claimed task so that a retry of the task can execute it.
+func (service *Service) release(r *http.Request, dogID, taskName string) {
+	err := service.TasksClient.Release(r.Context(), dogID, taskName)
+	if err != nil {
+		service.Logf(r, `action=ReleaseTask dogID=%s taskName=%s result=InternalError errorText="%s"`,
+			dogID, taskName, err)
+		return
+	}
+	service.Logf(r, `action=ReleaseTask dogID=%s taskName=%s result=OK`, dogID, taskName)
+}
+
+// deliver sends a bark to a delivery channel, logs the result and returns the history record of
+// the attempt.
+func (service *Service) deliver(r *http.Request, b *Bark, channel string) *BarkRecord {
+	barker, err := service.Barkers.Get(channel)
+	if err == nil {
+		err = barker.Bark(r.Context(), b)
+	}
+
+	record := &BarkRecord{
+		ID:            b.ID,
+		DogID:         b.Dog.ID,
+		IdeaID:        b.Idea.ID,
+		TaskName:      b.TaskName,
+		ScheduledTime: b.ScheduledTime,
+		ActualTime:    time.Now(),
+		Channel:       channel,
+		Address:       b.Address,
+		Attempt:       b.Attempt,
+		Outcome:       BarkDelivered,
+	}
+	if err != nil {
+		service.Logf(r, `action=Bark barkID=%s dogID=%s ideaID=%s channel=%s attempt=%d result=DeliveryError errorText="%s"`,
+			b.ID, b.Dog.ID, b.Idea.ID, channel, b.Attempt, err)
+		record.Outcome = BarkFailed
+		record.ErrorText = err.Error()
+	} else {
+		service.Logf(r, `action=Bark barkID=%s dogID=%s ideaID=%s channel=%s attempt=%d result=OK`,
+			b.ID, b.Dog.ID, b.Idea.ID, channel, b.Attempt)
+	}
+	return record
+}
+
+// ListBarksResponse is the response type for listing bark history.
+type ListBarksResponse struct {
+	Barks         []*BarkRecord `json:"barks"`
+	NextPageToken string        `json:"nextPageToken,omitempty"`
+}
+
+const (
+	defaultBarksPageSize = 20
+	maxBarksPageSize     = 100
+)
+
+// parseSchedule parses and validates a requested schedule with its bounds and quiet hours,
+// responding with an error if it cannot. The schedule is returned in its normalized form, with any
+// defaults filled in.
+func (service *Service) parseSchedule(w http.ResponseWriter, r *http.Request, scheduleType string,
+	raw json.RawMessage, bounds *Bounds, quiet QuietHours) (json.RawMessage, bool) {
+
+	schedule, err := ParseSchedule(scheduleType, raw)
+	if err != nil {
+		service.Logf(r, `result=ParseScheduleError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	normalized, err := json.Marshal(schedule)
+	if err != nil {
+		service.Logf(r, `result=MarshalScheduleError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return nil, false
+	}
+
+	err = bounds.Validate()
+	if err != nil {
+		service.Logf(r, `result=InvalidBoundsError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	err = quiet.Validate()
+	if err != nil {
+		service.Logf(r, `result=InvalidQuietHoursError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	if bounds.Wrap(quiet.Wrap(schedule), 0).Next(time.Now()).IsZero() {
+		service.Logf(r, `result=ScheduleExpiredError`)
+		bark.RespondError(w, http.StatusBadRequest, "schedule has no upcoming times")
+		return nil, false
+	}
+
+	return normalized, true
+}
+
+// pageParams parses the pageSize and pageToken query parameters of a list request.
+func pageParams(r *http.Request) (int, string, error) {
+	pageSize := defaultBarksPageSize
+	if pageSizeParam := r.URL.Query().Get("pageSize"); pageSizeParam != "" {
+		var err error
+		pageSize, err = strconv.Atoi(pageSizeParam)
+		if err != nil || pageSize < 1 || pageSize > maxBarksPageSize {
+			return 0, "", fmt.Errorf("pageSize must be between 1 and %d", maxBarksPageSize)
+		}
+	}
+	return pageSize, r.URL.Query().Get("pageToken"), nil
+}
+
+// ListDogBarks is a handler for listing a dog's bark history, most recent first.
+// Results are paginated with the pageSize and pageToken query parameters.
+func (service *Service) ListDogBarks(w http.ResponseWriter, r *http.Request) {
+	dogID := chi.URLParam(r, "dogID")
+
+	pageSize, pageToken, err := pageParams(r)
+	if err != nil {
+		service.Logf(r, `result=InvalidPageSizeError`)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, nextPageToken, err := service.BarkStore.ListByDog(r.Context(), dogID, pageSize, pageToken)
+	switch err {
+	case nil:
+		service.Logf(r, `action=ListBarks dogID=%s count=%d result=OK`, dogID, len(records))
+		bark.RespondSuccess(w, http.StatusOK, &ListBarksResponse{
+			Barks:         records,
+			NextPageToken: nextPageToken,
+		})
+	case ErrInvalidPageToken:
+		service.Logf(r, `action=ListBarks dogID=%s result=InvalidPageTokenError`, dogID)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+	default:
+		service.Logf(r, `action=ListBarks dogID=%s result=InternalError errorText="%s"`,
+			dogID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+	}
+}
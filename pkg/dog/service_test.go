@@ -0,0 +1,299 @@
+package dog_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/dgravesa/bark/pkg/dog"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ideaMap is an in-memory IdeaGetter.
+type ideaMap map[string]*bark.Idea
+
+func (ideas ideaMap) Get(ctx context.Context, ID string) (*bark.Idea, error) {
+	idea, found := ideas[ID]
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "idea not found with ID: %s", ID)
+	}
+	return idea, nil
+}
+
+// memoryTasks is an in-memory TasksClient that registers dogs at their next scheduled time.
+// Methods not used by a test panic through the nil embedded interface.
+type memoryTasks struct {
+	dog.TasksClient
+	dogs map[string]*dog.Dog
+}
+
+func (tasks *memoryTasks) Register(ctx context.Context, d *dog.Dog) (*dog.Dog, error) {
+	schedule, err := d.Schedule()
+	if err != nil {
+		return d, err
+	}
+	d.NextTaskTime = schedule.Next(time.Now())
+	d.NextTaskName = "task-" + d.ID
+	if tasks.dogs == nil {
+		tasks.dogs = make(map[string]*dog.Dog)
+	}
+	tasks.dogs[d.ID] = d
+	return d, nil
+}
+
+func (tasks *memoryTasks) Revise(ctx context.Context, dogID string,
+	revise func(d *dog.Dog) (map[string]interface{}, error)) (*dog.Dog, error) {
+
+	stored, found := tasks.dogs[dogID]
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "dog not found with ID: %s", dogID)
+	}
+
+	// revise a copy without the stored dog's parsed schedule
+	b, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	var revised dog.Dog
+	if err = json.Unmarshal(b, &revised); err != nil {
+		return nil, err
+	}
+	if _, err = revise(&revised); err != nil {
+		return nil, err
+	}
+	return tasks.Register(ctx, &revised)
+}
+
+// serve sends a request with a JSON body to the service's routes and returns the recorded response.
+func serve(service *dog.Service, method, target, body string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	service.RegisterRoutes(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func TestPostDogSpaced(t *testing.T) {
+	tasks := &memoryTasks{}
+	service := &dog.Service{
+		IdeaGetter:  ideaMap{"idea1": {ID: "idea1", Text: "remember this"}},
+		TasksClient: tasks,
+		Barkers:     &dog.BarkerRegistry{},
+	}
+
+	body := `{"ideaId": "idea1", "scheduleType": "spaced", "schedule": {"firstInterval": "2d"}}`
+	w := httptest.NewRecorder()
+	start := time.Now()
+	service.PostDog(w, httptest.NewRequest(http.MethodPost, "/dogs", strings.NewReader(body)))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var created dog.Dog
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	registered, found := tasks.dogs[created.ID]
+	if !found {
+		t.Fatalf("dog %s was not registered", created.ID)
+	}
+	if got := string(registered.ScheduleRaw); !strings.Contains(got, `"firstInterval":"2d`) {
+		t.Errorf("schedule = %s, want normalized first interval of 2d", got)
+	}
+
+	// the first bark is one first interval after creation
+	wantNext := registered.CreationTime.Add(48 * time.Hour)
+	if next := registered.NextTaskTime; next.Before(start.Add(48*time.Hour)) || next.After(wantNext) {
+		t.Errorf("next task time = %s, want %s", next, wantNext)
+	}
+}
+
+func TestPostDogAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantCode int
+	}{
+		{name: "future time", at: time.Now().Add(time.Hour), wantCode: http.StatusCreated},
+		{name: "past time", at: time.Now().Add(-time.Hour), wantCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tasks := &memoryTasks{}
+			service := &dog.Service{
+				IdeaGetter:  ideaMap{"idea1": {ID: "idea1", Text: "remember this"}},
+				TasksClient: tasks,
+				Barkers:     &dog.BarkerRegistry{},
+			}
+
+			body := `{"ideaId": "idea1", "scheduleType": "at", "schedule": "` +
+				test.at.UTC().Format(time.RFC3339) + `"}`
+			w := serve(service, http.MethodPost, "/dogs", body)
+			if w.Code != test.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantCode, w.Body)
+			}
+			if w.Code != http.StatusCreated && len(tasks.dogs) != 0 {
+				t.Errorf("registered %d dogs, want none", len(tasks.dogs))
+			}
+		})
+	}
+}
+
+func TestPostFeedback(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	tasks := &memoryTasks{}
+	tasks.Register(context.Background(), &dog.Dog{
+		ID:           "spaced",
+		CreationTime: created,
+		ScheduleType: "spaced",
+		ScheduleRaw:  json.RawMessage(`{"firstInterval":"1d","secondInterval":"6d","maxInterval":"30d"}`),
+	})
+	tasks.Register(context.Background(), &dog.Dog{
+		ID:           "cron",
+		CreationTime: created,
+		ScheduleType: "cron",
+		ScheduleRaw:  json.RawMessage(`"0 9 * * *"`),
+	})
+	service := &dog.Service{TasksClient: tasks}
+
+	// each passing review moves the next bark further out
+	for _, wantInterval := range []time.Duration{24 * time.Hour, 6 * 24 * time.Hour} {
+		reviewed := time.Now()
+		w := serve(service, http.MethodPost, "/dogs/spaced/feedback", `{"rating": 5}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+		d := tasks.dogs["spaced"]
+		if d.Repetition == nil || d.Repetition.Interval != wantInterval {
+			t.Fatalf("repetition = %+v, want interval %s", d.Repetition, wantInterval)
+		}
+		if next := d.NextTaskTime; next.Before(reviewed.Add(wantInterval)) {
+			t.Errorf("next task time = %s, want %s after review", next, wantInterval)
+		}
+	}
+
+	// a failed review starts over
+	serve(service, http.MethodPost, "/dogs/spaced/feedback", `{"rating": 1}`)
+	if rep := tasks.dogs["spaced"].Repetition; rep.Repetitions != 0 || rep.Interval != 24*time.Hour {
+		t.Errorf("repetition = %+v, want reset to first interval", rep)
+	}
+
+	errorTests := []struct {
+		target, body string
+		wantCode     int
+	}{
+		{target: "/dogs/spaced/feedback", body: `{}`, wantCode: http.StatusBadRequest},
+		{target: "/dogs/spaced/feedback", body: `{"rating": 6}`, wantCode: http.StatusBadRequest},
+		{target: "/dogs/cron/feedback", body: `{"rating": 3}`, wantCode: http.StatusConflict},
+		{target: "/dogs/missing/feedback", body: `{"rating": 3}`, wantCode: http.StatusNotFound},
+	}
+	for _, test := range errorTests {
+		if w := serve(service, http.MethodPost, test.target, test.body); w.Code != test.wantCode {
+			t.Errorf("POST %s %s: status = %d, want %d", test.target, test.body, w.Code, test.wantCode)
+		}
+	}
+}
+
+// pagedBarks is an in-memory BarkStore that lists records, most recent first, in pages.
+type pagedBarks struct {
+	dog.BarkStore
+	records  []*dog.BarkRecord
+	pageSize int
+}
+
+func (store *pagedBarks) ListByDog(ctx context.Context, dogID string, pageSize int,
+	pageToken string) ([]*dog.BarkRecord, string, error) {
+
+	store.pageSize = pageSize
+	var records []*dog.BarkRecord
+	for _, record := range store.records {
+		if record.DogID == dogID {
+			records = append(records, record)
+		}
+	}
+	if pageToken != "" {
+		start := -1
+		for i, record := range records {
+			if record.ID == pageToken {
+				start = i + 1
+			}
+		}
+		if start < 0 {
+			return nil, "", dog.ErrInvalidPageToken
+		}
+		records = records[start:]
+	}
+	if len(records) < pageSize {
+		return records, "", nil
+	}
+	return records[:pageSize], records[pageSize-1].ID, nil
+}
+
+func TestListDogBarks(t *testing.T) {
+	store := &pagedBarks{records: []*dog.BarkRecord{
+		{ID: "bark3", DogID: "dog1"},
+		{ID: "other", DogID: "dog2"},
+		{ID: "bark2", DogID: "dog1"},
+		{ID: "bark1", DogID: "dog1"},
+	}}
+	service := &dog.Service{BarkStore: store}
+
+	tests := []struct {
+		name          string
+		query         string
+		wantCode      int
+		wantPageSize  int
+		wantBarks     string
+		wantPageToken string
+	}{
+		{name: "default page size", query: "", wantCode: http.StatusOK, wantPageSize: 20, wantBarks: "bark3 bark2 bark1"},
+		{name: "first page", query: "?pageSize=2", wantCode: http.StatusOK, wantPageSize: 2, wantBarks: "bark3 bark2", wantPageToken: "bark2"},
+		{name: "next page", query: "?pageSize=2&pageToken=bark2", wantCode: http.StatusOK, wantPageSize: 2, wantBarks: "bark1"},
+		{name: "full last page", query: "?pageSize=1&pageToken=bark2", wantCode: http.StatusOK, wantPageSize: 1, wantBarks: "bark1", wantPageToken: "bark1"},
+		{name: "empty page", query: "?pageSize=1&pageToken=bark1", wantCode: http.StatusOK, wantPageSize: 1},
+		{name: "maximum page size", query: "?pageSize=100", wantCode: http.StatusOK, wantPageSize: 100, wantBarks: "bark3 bark2 bark1"},
+		{name: "zero page size", query: "?pageSize=0", wantCode: http.StatusBadRequest},
+		{name: "page size too large", query: "?pageSize=101", wantCode: http.StatusBadRequest},
+		{name: "page size not a number", query: "?pageSize=ten", wantCode: http.StatusBadRequest},
+		{name: "invalid page token", query: "?pageToken=unknown", wantCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store.pageSize = 0
+			w := serve(service, http.MethodGet, "/dogs/dog1/barks"+test.query, "")
+			if w.Code != test.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantCode, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response dog.ListBarksResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			IDs := []string{}
+			for _, record := range response.Barks {
+				IDs = append(IDs, record.ID)
+			}
+			if got := strings.Join(IDs, " "); got != test.wantBarks {
+				t.Errorf("barks = %q, want %q", got, test.wantBarks)
+			}
+			if response.NextPageToken != test.wantPageToken {
+				t.Errorf("next page token = %q, want %q", response.NextPageToken, test.wantPageToken)
+			}
+			if store.pageSize != test.wantPageSize {
+				t.Errorf("listed with page size %d, want %d", store.pageSize, test.wantPageSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package dog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Solar events of a SolarSchedule.
+const (
+	Sunrise   = "sunrise"
+	Sunset    = "sunset"
+	SolarNoon = "noon"
+)
+
+// maxSolarOffset is the largest offset allowed from a solar event.
+const maxSolarOffset = 12 * time.Hour
+
+// maxSolarDays bounds how many days ahead a SolarSchedule looks for its next event, such as
+// through a polar night.
+const maxSolarDays = 370
+
+// solarZenith is the zenith angle of the sun at sunrise and sunset, in degrees, accounting for
+// atmospheric refraction and the size of the sun's disc.
+const solarZenith = 90.833
+
+// SolarSchedule is a schedule that fires daily at an offset from sunrise, sunset or solar noon at
+// a location. Event times are calculated with the NOAA solar equations and are accurate to about
+// a minute. Days on which the event does not occur, as in polar days and nights, are skipped.
+type SolarSchedule struct {
+	Event     string
+	Offset    time.Duration
+	Latitude  float64
+	Longitude float64
+}
+
+func init() {
+	RegisterScheduleType("solar", func(b json.RawMessage) (Schedule, error) {
+		var schedule SolarSchedule
+		err := json.Unmarshal(b, &schedule)
+		return schedule, err
+	})
+}
+
+// solarScheduleJSON is the JSON form of a SolarSchedule.
+type solarScheduleJSON struct {
+	Event     string   `json:"event"`
+	Offset    string   `json:"offset,omitempty"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// Next implements the Schedule interface.
+func (s SolarSchedule) Next(t time.Time) time.Time {
+	// start from the day before, since an event of that day may be after t once offset
+	utc := t.UTC()
+	day := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	for i := 0; i < maxSolarDays; i++ {
+		event, ok := solarEvent(s.Event, day, s.Latitude, s.Longitude)
+		if ok {
+			if next := event.Add(s.Offset); next.After(t) {
+				return next.In(t.Location())
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// UnmarshalJSON parses a JSON object with an "event" of "sunrise", "sunset" or "noon", an
+// optional "offset" duration such as "30m" or "-1h", and a "latitude" and "longitude" in degrees.
+func (s *SolarSchedule) UnmarshalJSON(b []byte) error {
+	var raw solarScheduleJSON
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	switch raw.Event {
+	case Sunrise, Sunset, SolarNoon:
+		s.Event = raw.Event
+	default:
+		return fmt.Errorf("invalid solar event: %s", raw.Event)
+	}
+
+	s.Offset = 0
+	if raw.Offset != "" {
+		s.Offset, err = time.ParseDuration(raw.Offset)
+		if err != nil || s.Offset < -maxSolarOffset || s.Offset > maxSolarOffset {
+			return fmt.Errorf("invalid solar offset: %s", raw.Offset)
+		}
+	}
+
+	if raw.Latitude == nil || *raw.Latitude < -90 || *raw.Latitude > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if raw.Longitude == nil || *raw.Longitude < -180 || *raw.Longitude > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	s.Latitude = *raw.Latitude
+	s.Longitude = *raw.Longitude
+	return nil
+}
+
+// MarshalJSON writes the event, offset and location as a JSON object.
+func (s SolarSchedule) MarshalJSON() ([]byte, error) {
+	raw := solarScheduleJSON{
+		Event:     s.Event,
+		Latitude:  &s.Latitude,
+		Longitude: &s.Longitude,
+	}
+	if s.Offset != 0 {
+		raw.Offset = s.Offset.String()
+	}
+	return json.Marshal(&raw)
+}
+
+// solarEvent returns the time of a solar event on a UTC date at a location, or false if the event
+// does not occur on that date.
+func solarEvent(event string, date time.Time, latitude, longitude float64) (time.Time, bool) {
+	// estimate the event at solar noon, then refine it at the estimated time
+	minutes := 720 - 4*longitude
+	for i := 0; i < 2; i++ {
+		eqTime, declination := solarPosition(date.Add(time.Duration(minutes * float64(time.Minute))))
+		noon := 720 - 4*longitude - eqTime
+		if event == SolarNoon {
+			minutes = noon
+			continue
+		}
+
+		cosHourAngle := math.Cos(radians(solarZenith))/
+			(math.Cos(radians(latitude))*math.Cos(declination)) -
+			math.Tan(radians(latitude))*math.Tan(declination)
+		if cosHourAngle < -1 || cosHourAngle > 1 {
+			return time.Time{}, false
+		}
+		hourAngle := degrees(math.Acos(cosHourAngle))
+		if event == Sunrise {
+			minutes = noon - 4*hourAngle
+		} else {
+			minutes = noon + 4*hourAngle
+		}
+	}
+	return date.Add(time.Duration(minutes * float64(time.Minute))).Truncate(time.Second), true
+}
+
+// solarPosition returns the equation of time, in minutes, and the declination of the sun, in
+// radians, at t.
+func solarPosition(t time.Time) (float64, float64) {
+	julianDay := float64(t.Unix())/86400 + 2440587.5
+	century := (julianDay - 2451545) / 36525
+
+	meanLongitude := math.Mod(280.46646+century*(36000.76983+century*0.0003032), 360)
+	meanAnomaly := 357.52911 + century*(35999.05029-0.0001537*century)
+	eccentricity := 0.016708634 - century*(0.000042037+0.0000001267*century)
+
+	m := radians(meanAnomaly)
+	center := math.Sin(m)*(1.914602-century*(0.004817+0.000014*century)) +
+		math.Sin(2*m)*(0.019993-0.000101*century) +
+		math.Sin(3*m)*0.000289
+	omega := radians(125.04 - 1934.136*century)
+	apparentLongitude := meanLongitude + center - 0.00569 - 0.00478*math.Sin(omega)
+
+	meanObliquity := 23 + (26+(21.448-century*(46.815+century*(0.00059-century*0.001813)))/60)/60
+	obliquity := radians(meanObliquity + 0.00256*math.Cos(omega))
+	declination := math.Asin(math.Sin(obliquity) * math.Sin(radians(apparentLongitude)))
+
+	y := math.Pow(math.Tan(obliquity/2), 2)
+	l := radians(meanLongitude)
+	eqTime := 4 * degrees(y*math.Sin(2*l)-
+		2*eccentricity*math.Sin(m)+
+		4*eccentricity*y*math.Sin(m)*math.Cos(2*l)-
+		0.5*y*y*math.Sin(4*l)-
+		1.25*eccentricity*eccentricity*math.Sin(2*m))
+
+	return eqTime, declination
+}
+
+// radians converts degrees to radians.
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// degrees converts radians to degrees.
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
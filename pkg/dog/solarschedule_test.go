@@ -0,0 +1,114 @@
+package dog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSolarScheduleNext(t *testing.T) {
+	// expected times are from the NOAA solar calculator, rounded to the minute
+	tests := []struct {
+		name  string
+		raw   string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "London sunrise at midsummer",
+			raw:   `{"event": "sunrise", "latitude": 51.5074, "longitude": -0.1278}`,
+			after: time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 6, 21, 3, 43, 0, 0, time.UTC),
+		},
+		{
+			name:  "London sunset at midsummer",
+			raw:   `{"event": "sunset", "latitude": 51.5074, "longitude": -0.1278}`,
+			after: time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 6, 21, 20, 21, 0, 0, time.UTC),
+		},
+		{
+			name:  "New York sunset less an hour",
+			raw:   `{"event": "sunset", "offset": "-1h", "latitude": 40.7128, "longitude": -74.006}`,
+			after: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 1, 1, 20, 39, 0, 0, time.UTC),
+		},
+		{
+			name:  "Sydney sunrise on the previous UTC date",
+			raw:   `{"event": "sunrise", "latitude": -33.8688, "longitude": 151.2093}`,
+			after: time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 12, 20, 18, 41, 0, 0, time.UTC),
+		},
+		{
+			name:  "solar noon on the prime meridian",
+			raw:   `{"event": "noon", "latitude": 0, "longitude": 0}`,
+			after: time.Date(2024, 11, 3, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 11, 3, 11, 44, 0, 0, time.UTC),
+		},
+		{
+			name:  "after today's event",
+			raw:   `{"event": "sunrise", "latitude": 51.5074, "longitude": -0.1278}`,
+			after: time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 6, 22, 3, 43, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseSchedule("solar", json.RawMessage(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := schedule.Next(test.after)
+			if diff := got.Sub(test.want); diff < -2*time.Minute || diff > 2*time.Minute {
+				t.Errorf("Next(%s) = %s, want %s", test.after, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSolarSchedulePolar(t *testing.T) {
+	tromso := SolarSchedule{Event: Sunrise, Latitude: 69.6492, Longitude: 18.9553}
+
+	// the sun does not rise through the polar night until mid-January
+	next := tromso.Next(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	if next.Before(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)) || next.After(time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Next() = %s, want mid-January", next)
+	}
+
+	// nor set through the midnight sun
+	tromso.Event = Sunset
+	next = tromso.Next(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	if next.Before(time.Date(2024, 7, 20, 0, 0, 0, 0, time.UTC)) || next.After(time.Date(2024, 7, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Next() = %s, want late July", next)
+	}
+
+	// the search for an event that does not occur on any day ends
+	pole := SolarSchedule{Event: Sunrise, Latitude: 90}
+	if next = pole.Next(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Errorf("Next() at the pole = %s, want zero time", next)
+	}
+}
+
+func TestSolarScheduleJSON(t *testing.T) {
+	var s SolarSchedule
+	raw := `{"event":"sunset","offset":"-30m0s","latitude":35.68,"longitude":139.69}`
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := json.Marshal(s); err != nil || string(b) != raw {
+		t.Errorf("Marshal() = %s, %v, want %s", b, err, raw)
+	}
+
+	for _, raw := range []string{
+		`{"event": "dusk", "latitude": 0, "longitude": 0}`,
+		`{"event": "sunset", "offset": "13h", "latitude": 0, "longitude": 0}`,
+		`{"event": "sunset", "offset": "soon", "latitude": 0, "longitude": 0}`,
+		`{"event": "sunset", "longitude": 0}`,
+		`{"event": "sunset", "latitude": 91, "longitude": 0}`,
+		`{"event": "sunset", "latitude": 0, "longitude": -181}`,
+	} {
+		if err := json.Unmarshal([]byte(raw), &s); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", raw)
+		}
+	}
+}
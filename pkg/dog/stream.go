@@ -0,0 +1,218 @@
+package dog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamHeartbeatInterval is how often a comment is sent to keep idle streams open.
+const streamHeartbeatInterval = 15 * time.Second
+
+// streamPollInterval is how often streams poll the bark history for barks delivered by other
+// instances of the service.
+const streamPollInterval = 5 * time.Second
+
+// maxStreamResumeRecords bounds the records read from the bark history by each poll of a stream.
+const maxStreamResumeRecords = 100
+
+// RegisterStreamRoutes registers the long-lived streaming routes to a chi router. These routes
+// must not be subject to a request timeout.
+func (service *Service) RegisterStreamRoutes(r chi.Router) {
+	r.Get("/barks/stream", service.StreamBarks)
+}
+
+// StreamBarks is a handler that streams delivered barks as Server-Sent Events.
+//
+// Each event's data is the JSON bark record. Clients resuming with a Last-Event-ID header first
+// receive the barks delivered since that bark. Streams may be restricted to a single dog with the
+// dogId query parameter.
+//
+// Streams poll the bark history so that they receive the barks delivered by every instance of the
+// service, and events polled from the history have the ID of their bark record. Barks delivered by
+// this instance are sent as soon as they are published to the hub, without an event ID, since
+// resuming from them could skip barks of other instances that are yet to be polled.
+func (service *Service) StreamBarks(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		service.Logf(r, `result=StreamingUnsupportedError`)
+		bark.RespondError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	stream := &barkStream{
+		store: service.BarkStore,
+		dogID: r.URL.Query().Get("dogId"),
+		sent:  make(map[string]bool),
+	}
+
+	records, unsubscribe := service.Hub.Subscribe()
+	defer unsubscribe()
+
+	// resume after the last event, or otherwise start after the latest bark
+	var err error
+	stream.cursor, err = service.streamCursor(r, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	service.Logf(r, `action=StreamBarks result=Open`)
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	poll := time.NewTimer(0)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			service.Logf(r, `action=StreamBarks result=Closed`)
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case record := <-records:
+			if !stream.receive(record) {
+				continue
+			}
+			if err := writeBarkEvent(w, "", record); err != nil {
+				return
+			}
+		case <-poll.C:
+			polled, more, err := stream.poll(r.Context())
+			if err != nil {
+				service.Logf(r, `action=ListBarksSince lastEventID=%s result=InternalError errorText="%s"`,
+					stream.cursor, err)
+			}
+			for _, record := range polled {
+				if err := writeBarkEvent(w, record.ID, record); err != nil {
+					return
+				}
+			}
+			if more {
+				poll.Reset(0)
+			} else {
+				poll.Reset(streamPollInterval)
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// streamCursor returns the ID of the bark record after which a stream starts. Streams resume after
+// the last event if it exists, and otherwise start after the latest bark. The cursor is empty if
+// there are no barks.
+func (service *Service) streamCursor(r *http.Request, lastEventID string) (string, error) {
+	if lastEventID != "" {
+		_, err := service.BarkStore.Get(r.Context(), lastEventID)
+		switch status.Code(err) {
+		case codes.OK:
+			service.Logf(r, `action=GetBarkRecord barkID=%s result=OK`, lastEventID)
+			return lastEventID, nil
+		case codes.NotFound:
+			// unknown events cannot be resumed from, so only new barks are streamed
+			service.Logf(r, `action=GetBarkRecord barkID=%s result=NotFound`, lastEventID)
+		default:
+			service.Logf(r, `action=GetBarkRecord barkID=%s result=InternalError errorText="%s"`,
+				lastEventID, err)
+			return "", err
+		}
+	}
+
+	latest, err := service.BarkStore.Latest(r.Context())
+	if err != nil {
+		service.Logf(r, `action=GetLatestBarkRecord result=InternalError errorText="%s"`, err)
+		return "", err
+	}
+	if latest == nil {
+		return "", nil
+	}
+	return latest.ID, nil
+}
+
+// barkStream tracks the barks sent to a stream from the bark history and the hub.
+type barkStream struct {
+	store BarkStore
+	dogID string
+	// cursor is the ID of the last bark record polled from the history
+	cursor string
+	// sent holds the IDs of barks sent from the hub that have not yet been polled. It is cleared
+	// when a poll catches up with the history, since barks published before then that were not
+	// polled were never recorded.
+	sent map[string]bool
+	// polled holds the IDs of barks from the last poll, which may still be published to the hub
+	polled map[string]bool
+}
+
+// poll returns the barks recorded since the last poll that are to be sent to the stream, and
+// whether more barks may remain to be polled.
+func (stream *barkStream) poll(ctx context.Context) ([]*BarkRecord, bool, error) {
+	records, err := stream.store.ListSince(ctx, stream.cursor, maxStreamResumeRecords)
+	if err != nil {
+		return nil, false, err
+	}
+
+	polled := make(map[string]bool, len(records))
+	var send []*BarkRecord
+	for _, record := range records {
+		stream.cursor = record.ID
+		polled[record.ID] = true
+		if stream.sent[record.ID] {
+			delete(stream.sent, record.ID)
+			continue
+		}
+		if stream.wants(record) {
+			send = append(send, record)
+		}
+	}
+	stream.polled = polled
+
+	more := len(records) == maxStreamResumeRecords
+	if !more {
+		stream.sent = make(map[string]bool)
+	}
+	return send, more, nil
+}
+
+// receive returns whether a record published to the hub is to be sent to the stream.
+func (stream *barkStream) receive(record *BarkRecord) bool {
+	if stream.polled[record.ID] || !stream.wants(record) {
+		return false
+	}
+	stream.sent[record.ID] = true
+	return true
+}
+
+// wants returns whether a bark belongs in the stream.
+func (stream *barkStream) wants(record *BarkRecord) bool {
+	return record.Outcome == BarkDelivered && (stream.dogID == "" || record.DogID == stream.dogID)
+}
+
+// writeBarkEvent writes a bark record as a Server-Sent Event, with an ID line unless ID is empty.
+func writeBarkEvent(w http.ResponseWriter, ID string, record *BarkRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if ID != "" {
+		if _, err = fmt.Fprintf(w, "id: %s\n", ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: bark\ndata: %s\n\n", data)
+	return err
+}
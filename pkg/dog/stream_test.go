@@ -0,0 +1,122 @@
+package dog
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// barkHistory is an in-memory BarkStore of records in the order they were recorded.
+type barkHistory struct {
+	BarkStore
+	records []*BarkRecord
+}
+
+func (history *barkHistory) ListSince(ctx context.Context, ID string, limit int) ([]*BarkRecord, error) {
+	start := 0
+	if ID != "" {
+		start = -1
+		for i, record := range history.records {
+			if record.ID == ID {
+				start = i + 1
+			}
+		}
+		if start < 0 {
+			return nil, ErrInvalidPageToken
+		}
+	}
+	records := history.records[start:]
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (history *barkHistory) record(ID, dogID, outcome string) *BarkRecord {
+	record := &BarkRecord{ID: ID, DogID: dogID, Outcome: outcome}
+	history.records = append(history.records, record)
+	return record
+}
+
+func streamIDs(records []*BarkRecord) []string {
+	IDs := []string{}
+	for _, record := range records {
+		IDs = append(IDs, record.ID)
+	}
+	return IDs
+}
+
+func TestBarkStream(t *testing.T) {
+	history := &barkHistory{}
+	history.record("old", "dog1", BarkDelivered)
+	stream := &barkStream{store: history, dogID: "dog1", cursor: "old", sent: make(map[string]bool)}
+
+	// barks of this instance are sent from the hub, and barks of other instances from the history
+	local := history.record("local", "dog1", BarkDelivered)
+	if !stream.receive(local) {
+		t.Error("receive(local) = false, want true")
+	}
+	history.record("remote", "dog1", BarkDelivered)
+	history.record("failed", "dog1", BarkFailed)
+	history.record("other dog", "dog2", BarkDelivered)
+
+	polled, more, err := stream.poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(streamIDs(polled)); got != "[remote]" || more {
+		t.Errorf("poll() = %s, %v, want [remote], false", got, more)
+	}
+
+	// a bark published after it was polled is not sent again
+	late := history.record("late", "dog1", BarkDelivered)
+	if polled, _, _ = stream.poll(context.Background()); len(polled) != 1 {
+		t.Fatalf("poll() = %v, want [late]", streamIDs(polled))
+	}
+	if stream.receive(late) {
+		t.Error("receive(late) = true after poll, want false")
+	}
+
+	// polls page through a long history
+	for i := 0; i < maxStreamResumeRecords+1; i++ {
+		history.record(fmt.Sprint("bark", i), "dog1", BarkDelivered)
+	}
+	if polled, more, _ = stream.poll(context.Background()); len(polled) != maxStreamResumeRecords || !more {
+		t.Errorf("poll() returned %d records, %v, want %d, true", len(polled), more, maxStreamResumeRecords)
+	}
+	if polled, more, _ = stream.poll(context.Background()); len(polled) != 1 || more {
+		t.Errorf("poll() returned %d records, %v, want 1, false", len(polled), more)
+	}
+	if polled, _, _ = stream.poll(context.Background()); len(polled) != 0 {
+		t.Errorf("poll() = %v, want none", streamIDs(polled))
+	}
+
+	// barks that were sent but never recorded are forgotten once the stream catches up
+	stream.receive(&BarkRecord{ID: "unrecorded", DogID: "dog1", Outcome: BarkDelivered})
+	stream.poll(context.Background())
+	if len(stream.sent) != 0 {
+		t.Errorf("sent = %v after catching up, want none", stream.sent)
+	}
+}
+
+func TestWriteBarkEvent(t *testing.T) {
+	record := &BarkRecord{ID: "bark1", DogID: "dog1", Outcome: BarkDelivered}
+
+	polled := httptest.NewRecorder()
+	if err := writeBarkEvent(polled, record.ID, record); err != nil {
+		t.Fatal(err)
+	}
+	if got := polled.Body.String(); !strings.HasPrefix(got, "id: bark1\nevent: bark\ndata: {") {
+		t.Errorf("polled event = %q, want ID of its record", got)
+	}
+
+	published := httptest.NewRecorder()
+	if err := writeBarkEvent(published, "", record); err != nil {
+		t.Fatal(err)
+	}
+	if got := published.Body.String(); strings.Contains(got, "id:") {
+		t.Errorf("published event = %q, want no ID", got)
+	}
+}
@@ -0,0 +1,146 @@
+package dog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/dgravesa/bark/pkg/bark"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A User holds settings that apply to all of a user's dogs.
+type User struct {
+	ID         string     `json:"id" firestore:"id"`
+	QuietHours QuietHours `json:"quietHours" firestore:"quietHours"`
+	DigestID   string     `json:"digestId,omitempty" firestore:"digestId,omitempty"`
+}
+
+// UserGetter is an interface for getting users.
+type UserGetter interface {
+	Get(ctx context.Context, ID string) (*User, error)
+}
+
+// UserStore is a data store for users.
+type UserStore interface {
+	Get(ctx context.Context, ID string) (*User, error)
+	Set(ctx context.Context, user *User) error
+}
+
+var maxQuietHoursRequestSizeBytes int64 = 20000
+
+// GetQuietHours is a handler for getting a user's quiet hours.
+func (service *Service) GetQuietHours(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+
+	user, ok := service.getUser(w, r, userID)
+	if !ok {
+		return
+	}
+
+	bark.RespondSuccess(w, http.StatusOK, user)
+}
+
+// SetQuietHoursRequest is the request type for setting a user's quiet hours.
+type SetQuietHoursRequest struct {
+	QuietHours QuietHours `json:"quietHours"`
+}
+
+// PutQuietHours is a handler for setting a user's quiet hours. The quiet hours apply to each of
+// the user's dogs from their next scheduled bark.
+func (service *Service) PutQuietHours(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	var requestBody SetQuietHoursRequest
+
+	// read request body into struct
+	r.Body = http.MaxBytesReader(w, r.Body, maxQuietHoursRequestSizeBytes)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		service.Logf(r, `result=DecodeError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = requestBody.QuietHours.Validate(); err != nil {
+		service.Logf(r, `result=InvalidQuietHoursError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if requestBody.QuietHours == nil {
+		requestBody.QuietHours = QuietHours{}
+	}
+
+	user, ok := service.getUser(w, r, userID)
+	if !ok {
+		return
+	}
+	user.QuietHours = requestBody.QuietHours
+
+	service.setUser(w, r, user)
+}
+
+// SetUserDigestRequest is the request type for setting a user's digest.
+type SetUserDigestRequest struct {
+	DigestID string `json:"digestId"`
+}
+
+// PutUserDigest is a handler for setting the digest that the barks of all of a user's dogs are
+// collected into. An empty digest ID turns off digest mode for the user.
+func (service *Service) PutUserDigest(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	var requestBody SetUserDigestRequest
+
+	// read request body into struct
+	r.Body = http.MaxBytesReader(w, r.Body, maxQuietHoursRequestSizeBytes)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		service.Logf(r, `result=DecodeError errorText="%s"`, err)
+		bark.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if requestBody.DigestID != "" && !service.digestExists(w, r, requestBody.DigestID) {
+		return
+	}
+
+	user, ok := service.getUser(w, r, userID)
+	if !ok {
+		return
+	}
+	user.DigestID = requestBody.DigestID
+
+	service.setUser(w, r, user)
+}
+
+// getUser gets a user, or a user without settings if none exists, responding with an error if it
+// cannot.
+func (service *Service) getUser(w http.ResponseWriter, r *http.Request, userID string) (*User, bool) {
+	user, err := service.UserStore.Get(r.Context(), userID)
+	switch status.Code(err) {
+	case codes.OK:
+		service.Logf(r, `action=GetUser userID=%s result=OK`, userID)
+		return user, true
+	case codes.NotFound:
+		service.Logf(r, `action=GetUser userID=%s result=NotFound`, userID)
+		return &User{ID: userID, QuietHours: QuietHours{}}, true
+	default:
+		service.Logf(r, `action=GetUser userID=%s result=InternalError errorText="%s"`,
+			userID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return nil, false
+	}
+}
+
+// setUser writes a user and responds with it.
+func (service *Service) setUser(w http.ResponseWriter, r *http.Request, user *User) {
+	err := service.UserStore.Set(r.Context(), user)
+	if err != nil {
+		service.Logf(r, `action=SetUser userID=%s result=InternalError errorText="%s"`,
+			user.ID, err)
+		bark.RespondError(w, http.StatusInternalServerError, "internal error occurred")
+		return
+	}
+	service.Logf(r, `action=SetUser userID=%s result=OK`, user.ID)
+
+	bark.RespondSuccess(w, http.StatusOK, user)
+}
@@ -0,0 +1,34 @@
+package dog
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+)
+
+// UserFirestore is a Google Cloud Firestore-based data backend for users.
+type UserFirestore struct {
+	FirestoreClient *firestore.Client
+}
+
+// Get returns a user by ID.
+func (store *UserFirestore) Get(ctx context.Context, ID string) (*User, error) {
+	// get document from datastore
+	docID := "users/" + ID
+	userDoc, err := store.FirestoreClient.Doc(docID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert to user
+	var user User
+	err = userDoc.DataTo(&user)
+	return &user, err
+}
+
+// Set writes a user, replacing any existing user with the same key.
+func (store *UserFirestore) Set(ctx context.Context, user *User) error {
+	docID := "users/" + user.ID
+	_, err := store.FirestoreClient.Doc(docID).Set(ctx, user)
+	return err
+}
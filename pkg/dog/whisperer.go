@@ -7,6 +7,8 @@ import (
 
 	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
 	"google.golang.org/genproto/googleapis/cloud/tasks/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -16,48 +18,153 @@ type Whisperer struct {
 	QueueName  string
 	TaskClient *cloudtasks.Client
 	DogStore   DoggoStore
+	Digests    DigestStore
+	// Users, if set, provides user quiet hours for the dogs of users.
+	Users UserGetter
+	// Calendars, if set, provides the exclusion calendars of dogs.
+	Calendars CalendarGetter
 }
 
 // DoggoStore is a data store for dogs.
 type DoggoStore interface {
 	Get(ctx context.Context, ID string) (*Dog, error)
 	Put(ctx context.Context, dog *Dog) error
+	Update(ctx context.Context, dogID string, fields map[string]interface{}) error
+	Modify(ctx context.Context, dogID string,
+		modify func(dog *Dog) (map[string]interface{}, error)) (*Dog, error)
 	Delete(ctx context.Context, ID string) error
+	ClaimTask(ctx context.Context, dogID, taskName string) (*Dog, error)
+	ReleaseTask(ctx context.Context, dogID, taskName string) error
 }
 
 // Register registers a dog by initializing its task and putting it in the data store.
 // WARNING: on success, this method modifies the dog argument's NextTask fields.
 func (w Whisperer) Register(ctx context.Context, dog *Dog) (*Dog, error) {
-	// determine first scheduled time
-	schedule, err := dog.Schedule()
+	err := w.scheduleNext(ctx, dog)
+	if err != nil {
+		return dog, err
+	}
+
+	// insert into data store
+	return dog, w.DogStore.Put(ctx, dog)
+}
+
+// Reschedule creates the next task for an existing dog and updates its task fields, completion
+// and bark count in the data store. Other fields are left as they are, so that concurrent changes
+// to them are kept.
+// WARNING: on success, this method modifies the dog argument's NextTask fields.
+func (w Whisperer) Reschedule(ctx context.Context, dog *Dog) (*Dog, error) {
+	err := w.scheduleNext(ctx, dog)
+	if err != nil {
+		return dog, err
+	}
+
+	// update data store
+	return dog, w.DogStore.Update(ctx, dog.ID, map[string]interface{}{
+		"nextTaskName": dog.NextTaskName,
+		"nextTaskTime": dog.NextTaskTime,
+		"completed":    dog.Completed,
+		"barkCount":    dog.BarkCount,
+	})
+}
+
+// Revise transactionally modifies a dog with revise, which returns the fields that it changed by
+// their firestore names, then replaces the dog's pending task with one scheduled from now.
+// Deleting the replaced task is best effort, since it is stale and ignored if it executes.
+func (w Whisperer) Revise(ctx context.Context, dogID string,
+	revise func(dog *Dog) (map[string]interface{}, error)) (*Dog, error) {
+
+	dog, err := w.DogStore.Modify(ctx, dogID, revise)
 	if err != nil {
 		return nil, err
 	}
-	scheduleTime := schedule.Next(time.Now())
 
-	// create task
-	task, err := w.TaskClient.CreateTask(ctx, &tasks.CreateTaskRequest{
-		Parent: w.QueueName,
-		Task: &tasks.Task{
-			MessageType: &tasks.Task_AppEngineHttpRequest{
-				AppEngineHttpRequest: &tasks.AppEngineHttpRequest{
-					HttpMethod:  tasks.HttpMethod_POST,
-					RelativeUri: fmt.Sprintf("/dogs/%s/bark", dog.ID),
-				},
-			},
-			ScheduleTime: timestamppb.New(scheduleTime),
-		},
+	// update only task fields, since barks may have changed others since the revision
+	replacedTaskName := dog.NextTaskName
+	dog.NextTaskTime = time.Time{}
+	err = w.scheduleNext(ctx, dog)
+	if err != nil {
+		return dog, err
+	}
+	err = w.DogStore.Update(ctx, dog.ID, map[string]interface{}{
+		"nextTaskName": dog.NextTaskName,
+		"nextTaskTime": dog.NextTaskTime,
+		"completed":    dog.Completed,
 	})
 	if err != nil {
 		return dog, err
 	}
 
+	if replacedTaskName != "" {
+		w.TaskClient.DeleteTask(ctx, &tasks.DeleteTaskRequest{
+			Name: replacedTaskName,
+		})
+	}
+	return dog, nil
+}
+
+// Schedule returns the schedule of a dog, deferred by both the dog's and its user's quiet hours
+// and rolled forward past the excluded days of the dog's calendar, then limited by the dog's bounds.
+// Bounds apply last so that a time deferred past the end of the bounds is not scheduled.
+func (w Whisperer) Schedule(ctx context.Context, dog *Dog) (Schedule, error) {
+	schedule, err := dog.Schedule()
+	if err != nil {
+		return nil, err
+	}
+
+	// user quiet hours join the dog's, so that times are deferred until clear of both
+	if dog.UserID != "" && w.Users != nil {
+		user, err := w.Users.Get(ctx, dog.UserID)
+		switch status.Code(err) {
+		case codes.OK:
+			schedule = user.QuietHours.Wrap(schedule)
+		case codes.NotFound:
+			// users without settings have no quiet hours
+		default:
+			return nil, err
+		}
+	}
+
+	if dog.CalendarID != "" && w.Calendars != nil {
+		calendar, err := w.Calendars.Get(ctx, dog.CalendarID)
+		switch status.Code(err) {
+		case codes.OK:
+			schedule = calendar.Wrap(schedule)
+		case codes.NotFound:
+			// dogs of deleted calendars bark on every day
+		default:
+			return nil, err
+		}
+	}
+	return dog.Bounds.Wrap(schedule, dog.BarkCount), nil
+}
+
+// scheduleNext creates a task for the dog's next scheduled time and sets its NextTask fields.
+// If the schedule has no more times, the dog is marked completed and no task is created.
+func (w Whisperer) scheduleNext(ctx context.Context, dog *Dog) error {
+	// determine next scheduled time
+	schedule, err := w.Schedule(ctx, dog)
+	if err != nil {
+		return err
+	}
+	scheduleTime := schedule.Next(nextAfter(dog.NextTaskTime))
+	if scheduleTime.IsZero() {
+		dog.NextTaskName = ""
+		dog.NextTaskTime = time.Time{}
+		dog.Completed = true
+		return nil
+	}
+
+	// create task
+	task, err := w.createTask(ctx, "", fmt.Sprintf("/dogs/%s/bark", dog.ID), scheduleTime)
+	if err != nil {
+		return err
+	}
+
 	// update task fields of dog
 	dog.NextTaskName = task.Name
 	dog.NextTaskTime = task.ScheduleTime.AsTime()
-
-	// insert into data store
-	return dog, w.DogStore.Put(ctx, dog)
+	return nil
 }
 
 // Unregister deletes the dog and its associated task.
@@ -68,14 +175,163 @@ func (w Whisperer) Unregister(ctx context.Context, dogID string) error {
 		return err
 	}
 
-	// delete task
-	err = w.TaskClient.DeleteTask(ctx, &tasks.DeleteTaskRequest{
-		Name: dog.NextTaskName,
+	// delete task, unless the dog has completed
+	if dog.NextTaskName != "" {
+		err = w.TaskClient.DeleteTask(ctx, &tasks.DeleteTaskRequest{
+			Name: dog.NextTaskName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// delete dog
+	return w.DogStore.Delete(ctx, dogID)
+}
+
+// Claim claims a task for execution. The task name may be the full or short name of the task.
+// ErrTaskStale is returned if the task is not the dog's next task, and ErrTaskClaimed is returned
+// if the task is claimed by an execution whose lease has not expired.
+func (w Whisperer) Claim(ctx context.Context, dogID, taskName string) (*Dog, error) {
+	return w.DogStore.ClaimTask(ctx, dogID, taskName)
+}
+
+// Release releases a claimed task so that it may be claimed again.
+func (w Whisperer) Release(ctx context.Context, dogID, taskName string) error {
+	return w.DogStore.ReleaseTask(ctx, dogID, taskName)
+}
+
+// ScheduleRetry creates a task to retry delivery of a failed bark at the given time.
+// Retry tasks are named by bark and attempt, so scheduling the same retry twice has no effect.
+func (w Whisperer) ScheduleRetry(ctx context.Context, record *BarkRecord, scheduleTime time.Time) error {
+	nextAttempt := record.Attempt + 1
+	_, err := w.createTask(ctx,
+		fmt.Sprintf("retry-%s-%d", record.ID, nextAttempt),
+		fmt.Sprintf("/dogs/%s/barks/%s/retry?attempt=%d", record.DogID, record.ID, nextAttempt),
+		scheduleTime)
+	if status.Code(err) == codes.AlreadyExists {
+		return nil
+	}
+	return err
+}
+
+// ScheduleFollowUp creates a task to resurface a snoozed inbox item at the given time.
+// Follow-up tasks are named by item and time, so scheduling the same follow-up twice has no effect.
+func (w Whisperer) ScheduleFollowUp(ctx context.Context, item *InboxItem, scheduleTime time.Time) error {
+	_, err := w.createTask(ctx,
+		fmt.Sprintf("followup-%s-%d", item.ID, scheduleTime.Unix()),
+		fmt.Sprintf("/inbox/%s/resurface", item.ID),
+		scheduleTime)
+	if status.Code(err) == codes.AlreadyExists {
+		return nil
+	}
+	return err
+}
+
+// createTask creates a task that posts to a relative URI at the given time. If name is set, the
+// task is created with that short name so that creating it again fails with AlreadyExists.
+func (w Whisperer) createTask(ctx context.Context, name, relativeURI string,
+	scheduleTime time.Time) (*tasks.Task, error) {
+
+	task := &tasks.Task{
+		MessageType: &tasks.Task_AppEngineHttpRequest{
+			AppEngineHttpRequest: &tasks.AppEngineHttpRequest{
+				HttpMethod:  tasks.HttpMethod_POST,
+				RelativeUri: relativeURI,
+			},
+		},
+		ScheduleTime: timestamppb.New(scheduleTime),
+	}
+	if name != "" {
+		task.Name = fmt.Sprintf("%s/tasks/%s", w.QueueName, name)
+	}
+
+	return w.TaskClient.CreateTask(ctx, &tasks.CreateTaskRequest{
+		Parent: w.QueueName,
+		Task:   task,
 	})
+}
+
+// RegisterDigest registers a digest by initializing its task and putting it in the data store.
+// WARNING: on success, this method modifies the digest argument's NextTask fields.
+func (w Whisperer) RegisterDigest(ctx context.Context, digest *Digest) (*Digest, error) {
+	err := w.scheduleNextDigest(ctx, digest)
+	if err != nil {
+		return digest, err
+	}
+
+	// insert into data store
+	return digest, w.Digests.Put(ctx, digest)
+}
+
+// RescheduleDigest creates the next task for an existing digest and updates it in the data store.
+// WARNING: on success, this method modifies the digest argument's NextTask fields.
+func (w Whisperer) RescheduleDigest(ctx context.Context, digest *Digest) (*Digest, error) {
+	err := w.scheduleNextDigest(ctx, digest)
+	if err != nil {
+		return digest, err
+	}
+
+	// update data store
+	return digest, w.Digests.SetNextTask(ctx, digest.ID, digest.NextTaskName, digest.NextTaskTime)
+}
+
+// UnregisterDigest deletes the digest and its associated task.
+func (w Whisperer) UnregisterDigest(ctx context.Context, digestID string) error {
+	// retrieve digest document
+	digest, err := w.Digests.Get(ctx, digestID)
 	if err != nil {
 		return err
 	}
 
-	// delete dog
-	return w.DogStore.Delete(ctx, dogID)
+	// delete task, unless the digest has no more deliveries
+	if digest.NextTaskName != "" {
+		err = w.TaskClient.DeleteTask(ctx, &tasks.DeleteTaskRequest{
+			Name: digest.NextTaskName,
+		})
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	// delete digest
+	return w.Digests.Delete(ctx, digestID)
+}
+
+// ClaimDigest claims a digest task for execution. The returned digest holds its pending entries.
+func (w Whisperer) ClaimDigest(ctx context.Context, digestID, taskName string) (*Digest, error) {
+	return w.Digests.ClaimTask(ctx, digestID, taskName)
+}
+
+// scheduleNextDigest creates a task for the digest's next delivery and sets its NextTask fields.
+func (w Whisperer) scheduleNextDigest(ctx context.Context, digest *Digest) error {
+	schedule, err := digest.Schedule()
+	if err != nil {
+		return err
+	}
+	scheduleTime := schedule.Next(nextAfter(digest.NextTaskTime))
+	if scheduleTime.IsZero() {
+		digest.NextTaskName = ""
+		digest.NextTaskTime = time.Time{}
+		return nil
+	}
+
+	task, err := w.createTask(ctx, "", fmt.Sprintf("/digests/%s/send", digest.ID), scheduleTime)
+	if err != nil {
+		return err
+	}
+
+	digest.NextTaskName = task.Name
+	digest.NextTaskTime = task.ScheduleTime.AsTime()
+	return nil
+}
+
+// nextAfter returns the time after which to schedule the next task. This is the current time, or
+// the time of the previous task if a task is executed early, so that a time is never repeated.
+func nextAfter(previousTaskTime time.Time) time.Time {
+	now := time.Now()
+	if previousTaskTime.After(now) {
+		return previousTaskTime
+	}
+	return now
 }